@@ -20,5 +20,21 @@ func Migrate(db *gorm.DB) error {
 	return db.AutoMigrate(
 		&models.User{},
 		&models.Repository{},
+		&models.RepositoryLanguage{},
+		&models.Star{},
+		&models.RepositoryRedirect{},
+		&models.RepositoryTransfer{},
+		&models.PullMirror{},
+		&models.PushMirror{},
+		&models.RepositoryImport{},
+		&models.LFSObject{},
+		&models.LFSLock{},
+		&models.RepositoryStorage{},
+		&models.StorageLimit{},
+		&models.RepositoryHousekeeping{},
+		&models.IntegrityIssue{},
+		&models.AuditEvent{},
+		&models.CommitStatus{},
+		&models.Snippet{},
 	)
 }
@@ -0,0 +1,128 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BlameRange is a run of consecutive lines last changed by the same commit.
+type BlameRange struct {
+	Commit    Commit   `json:"commit"`
+	StartLine int      `json:"start_line"`
+	EndLine   int      `json:"end_line"`
+	Lines     []string `json:"lines"`
+}
+
+// Blame annotates path as of ref. When ignoreRevs is set, commits listed in
+// the repository's own .git-blame-ignore-revs (as of ref) are skipped.
+func (s *Service) Blame(repoPath, ref, path string, ignoreRevs bool) ([]BlameRange, error) {
+	args := []string{"blame", "--porcelain"}
+
+	if ignoreRevs {
+		if revs, err := s.ReadFile(repoPath, ref, ".git-blame-ignore-revs"); err == nil {
+			// Bare repositories have no work tree to read the file from
+			tempDir, err := os.MkdirTemp("", "git-blame-*")
+			if err != nil {
+				return nil, fmt.Errorf("failed to create temp directory: %w", err)
+			}
+			defer os.RemoveAll(tempDir)
+
+			revsFile := filepath.Join(tempDir, "ignore-revs")
+			if err := os.WriteFile(revsFile, revs, 0644); err != nil {
+				return nil, fmt.Errorf("failed to write ignore-revs file: %w", err)
+			}
+			args = append(args, "--ignore-revs-file", revsFile)
+		}
+	}
+
+	args = append(args, ref, "--", path)
+	output, err := runGit(repoPath, nil, nil, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to blame %s: %w", path, err)
+	}
+
+	return parseBlamePorcelain(output)
+}
+
+// parseBlamePorcelain parses `git blame --porcelain` output. Commit headers
+// are only printed the first time a commit appears, so they are remembered
+// by SHA for later lines.
+func parseBlamePorcelain(output []byte) ([]BlameRange, error) {
+	commits := make(map[string]*Commit)
+	ranges := []BlameRange{}
+
+	var current *Commit
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "\t") {
+			if current == nil {
+				return nil, fmt.Errorf("blame content before header")
+			}
+			content := line[1:]
+			last := len(ranges) - 1
+			if last >= 0 && ranges[last].Commit.SHA == current.SHA && ranges[last].EndLine == lineNumber-1 {
+				ranges[last].EndLine = lineNumber
+				ranges[last].Lines = append(ranges[last].Lines, content)
+			} else {
+				ranges = append(ranges, BlameRange{
+					Commit:    *current,
+					StartLine: lineNumber,
+					EndLine:   lineNumber,
+					Lines:     []string{content},
+				})
+			}
+			current = nil
+			continue
+		}
+
+		if current == nil {
+			// <sha> <original line> <final line> [<lines in group>]
+			fields := strings.Fields(line)
+			if len(fields) < 3 || len(fields[0]) < 40 {
+				return nil, fmt.Errorf("unexpected blame header %q", line)
+			}
+			number, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected blame header %q", line)
+			}
+			lineNumber = number
+
+			commit, ok := commits[fields[0]]
+			if !ok {
+				commit = &Commit{SHA: fields[0]}
+				commits[fields[0]] = commit
+			}
+			current = commit
+			continue
+		}
+
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			current.AuthorName = value
+		case "author-mail":
+			current.AuthorEmail = strings.Trim(value, "<>")
+		case "author-time":
+			timestamp, _ := strconv.ParseInt(value, 10, 64)
+			current.AuthoredAt = time.Unix(timestamp, 0).UTC()
+		case "summary":
+			current.Summary = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return ranges, nil
+}
@@ -0,0 +1,63 @@
+package git
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestBlame(t *testing.T) {
+	service, repoPath := newTestRepository(t, map[string]string{"app.go": "a\nb\nc\n"})
+	author := Signature{Name: "bob", Email: "bob@example.com"}
+
+	reformat, err := service.CommitFiles(repoPath, CommitOptions{
+		Branch:  "main",
+		Message: "Reformat",
+		Author:  author,
+		Files:   []FileChange{{Path: "app.go", Content: []byte("a\nB\nC\n")}},
+	})
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+
+	ranges, err := service.Blame(repoPath, "main", "app.go", true)
+	if err != nil {
+		t.Fatalf("Blame failed: %v", err)
+	}
+	if len(ranges) != 2 {
+		t.Fatalf("expected 2 ranges, got %+v", ranges)
+	}
+	first, second := ranges[0], ranges[1]
+	if first.StartLine != 1 || first.EndLine != 1 || first.Commit.Summary != "Initial commit" || first.Commit.AuthorEmail != "alice@example.com" {
+		t.Errorf("unexpected first range %+v", first)
+	}
+	if second.StartLine != 2 || second.EndLine != 3 || second.Commit.SHA != reformat || second.Commit.AuthorName != "bob" {
+		t.Errorf("unexpected second range %+v", second)
+	}
+	if len(second.Lines) != 2 || second.Lines[1] != "C" {
+		t.Errorf("unexpected lines %v", second.Lines)
+	}
+
+	// Ignoring the reformat attributes its lines to the original commit
+	_, err = service.CommitFiles(repoPath, CommitOptions{
+		Branch:  "main",
+		Message: "Ignore reformat",
+		Author:  author,
+		Files:   []FileChange{{Path: ".git-blame-ignore-revs", Content: []byte(fmt.Sprintf("# formatting\n%s\n", reformat))}},
+	})
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+
+	ranges, err = service.Blame(repoPath, "main", "app.go", true)
+	if err != nil {
+		t.Fatalf("Blame failed: %v", err)
+	}
+	if len(ranges) != 1 || ranges[0].EndLine != 3 || ranges[0].Commit.Summary != "Initial commit" {
+		t.Errorf("expected the reformat to be ignored, got %+v", ranges)
+	}
+
+	ranges, err = service.Blame(repoPath, "main", "app.go", false)
+	if err != nil || len(ranges) != 2 {
+		t.Errorf("expected ignore_revs=false to keep the reformat, got %+v (%v)", ranges, err)
+	}
+}
@@ -0,0 +1,189 @@
+package git
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoChanges is returned by CommitFiles when the resulting tree is identical
+// to the tree of the parent commit.
+var ErrNoChanges = errors.New("no changes to commit")
+
+// Signature identifies the author and committer of a commit.
+type Signature struct {
+	Name  string
+	Email string
+}
+
+// FileChange describes a single file written to or removed from a commit.
+type FileChange struct {
+	Path    string
+	Content []byte
+	Delete  bool
+	// Mode is the git file mode, e.g. "100755" for executables or "120000"
+	// for symlinks. It defaults to a regular file.
+	Mode string
+}
+
+// CommitOptions describes a commit created directly in a bare repository.
+type CommitOptions struct {
+	Branch  string
+	Message string
+	Author  Signature
+	Files   []FileChange
+}
+
+// runGit runs a git command inside repoPath and returns its stdout. Stderr is
+// included in the returned error so callers get git's own explanation.
+func runGit(repoPath string, env []string, stdin io.Reader, args ...string) ([]byte, error) {
+	return runGitContext(context.Background(), repoPath, env, stdin, args...)
+}
+
+// runGitContext is runGit for commands that may need to be cut short, such
+// as network operations.
+func runGitContext(ctx context.Context, repoPath string, env []string, stdin io.Reader, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdin = stdin
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// InitBareRepositoryAt initializes a bare repository at an arbitrary path with
+// the given initial branch.
+func (s *Service) InitBareRepositoryAt(repoPath, branch string) error {
+	if err := os.MkdirAll(repoPath, 0755); err != nil {
+		return fmt.Errorf("failed to create repo directory: %w", err)
+	}
+
+	if _, err := runGit(repoPath, nil, nil, "init", "--bare", "--initial-branch="+branch); err != nil {
+		return fmt.Errorf("failed to init bare repository: %w", err)
+	}
+	return nil
+}
+
+// DefaultBranch returns the branch HEAD points to in a bare repository.
+func (s *Service) DefaultBranch(repoPath string) (string, error) {
+	output, err := runGit(repoPath, nil, nil, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("failed to read default branch: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// SetDefaultBranch points HEAD of a bare repository at branch, which does not
+// need to exist yet.
+func (s *Service) SetDefaultBranch(repoPath, branch string) error {
+	if _, err := runGit(repoPath, nil, nil, "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+		return fmt.Errorf("failed to set default branch: %w", err)
+	}
+	return nil
+}
+
+// CommitFiles writes the given file changes on top of the tip of opts.Branch
+// (or as a root commit if the branch does not exist yet) without needing a
+// working tree, and returns the SHA of the new commit.
+func (s *Service) CommitFiles(repoPath string, opts CommitOptions) (string, error) {
+	ref := "refs/heads/" + opts.Branch
+
+	parent := ""
+	if output, err := runGit(repoPath, nil, nil, "rev-parse", "--verify", "--quiet", ref); err == nil {
+		parent = strings.TrimSpace(string(output))
+	}
+
+	// Build the new tree in a throwaway index so concurrent commits never
+	// share state.
+	tempDir, err := os.MkdirTemp("", "git-index-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+	indexEnv := []string{"GIT_INDEX_FILE=" + filepath.Join(tempDir, "index")}
+
+	if parent != "" {
+		if _, err := runGit(repoPath, indexEnv, nil, "read-tree", parent); err != nil {
+			return "", err
+		}
+	}
+
+	// All entries go to a single update-index run, NUL-separated so any
+	// path is safe. A zero mode removes the entry without a work tree.
+	var indexInfo bytes.Buffer
+	for _, change := range opts.Files {
+		if change.Delete {
+			fmt.Fprintf(&indexInfo, "0 %s\t%s\x00", strings.Repeat("0", 40), change.Path)
+			continue
+		}
+
+		blob, err := runGit(repoPath, nil, bytes.NewReader(change.Content), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return "", err
+		}
+		mode := change.Mode
+		if mode == "" {
+			mode = "100644"
+		}
+		fmt.Fprintf(&indexInfo, "%s %s\t%s\x00", mode, strings.TrimSpace(string(blob)), change.Path)
+	}
+	if _, err := runGit(repoPath, indexEnv, &indexInfo, "update-index", "-z", "--index-info"); err != nil {
+		return "", err
+	}
+
+	treeOutput, err := runGit(repoPath, indexEnv, nil, "write-tree")
+	if err != nil {
+		return "", err
+	}
+	tree := strings.TrimSpace(string(treeOutput))
+
+	if parent != "" {
+		parentTree, err := runGit(repoPath, nil, nil, "rev-parse", parent+"^{tree}")
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(string(parentTree)) == tree {
+			return "", ErrNoChanges
+		}
+	}
+
+	args := []string{"commit-tree", tree, "-m", opts.Message}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	authorEnv := []string{
+		"GIT_AUTHOR_NAME=" + opts.Author.Name,
+		"GIT_AUTHOR_EMAIL=" + opts.Author.Email,
+		"GIT_COMMITTER_NAME=" + opts.Author.Name,
+		"GIT_COMMITTER_EMAIL=" + opts.Author.Email,
+	}
+	commitOutput, err := runGit(repoPath, authorEnv, nil, args...)
+	if err != nil {
+		return "", err
+	}
+	commit := strings.TrimSpace(string(commitOutput))
+
+	// Only move the branch if nobody else moved it in the meantime.
+	oldValue := parent
+	if oldValue == "" {
+		oldValue = strings.Repeat("0", 40)
+	}
+	if _, err := runGit(repoPath, nil, nil, "update-ref", ref, commit, oldValue); err != nil {
+		return "", fmt.Errorf("failed to update %s: %w", ref, err)
+	}
+
+	return commit, nil
+}
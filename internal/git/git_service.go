@@ -1,6 +1,7 @@
 package git
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -9,7 +10,8 @@ import (
 )
 
 type Service struct {
-	reposPath string
+	reposPath        string
+	allowFileRemotes bool
 }
 
 func NewService(reposPath string) *Service {
@@ -24,8 +26,9 @@ func (s *Service) InitBareRepository(username, repoName string) error {
 		return fmt.Errorf("failed to create repo directory: %w", err)
 	}
 
-	// Initialize bare repository
-	cmd := exec.Command("git", "init", "--bare")
+	// Initialize bare repository, defaulting to the same "main" branch the
+	// README commit uses
+	cmd := exec.Command("git", "init", "--bare", "--initial-branch=main")
 	cmd.Dir = repoPath
 	// Remove stdout/stderr redirection to avoid interfering with HTTP responses
 	// cmd.Stdout = os.Stdout
@@ -48,6 +51,38 @@ func (s *Service) RepositoryExists(username, repoName string) bool {
 	return err == nil
 }
 
+// ErrRepositoryExists is returned when a repository would be moved onto a path
+// that is already taken.
+var ErrRepositoryExists = errors.New("repository already exists")
+
+// ErrRepositoryNotFound is returned when a repository to be moved is not on
+// disk.
+var ErrRepositoryNotFound = errors.New("repository not found on disk")
+
+// MoveRepository moves the bare repository and its wiki, if any, to a new
+// owner and/or name. The bare repository itself must exist.
+func (s *Service) MoveRepository(fromUser, fromName, toUser, toName string) error {
+	moves := [][2]string{
+		{s.GetRepositoryPath(fromUser, fromName), s.GetRepositoryPath(toUser, toName)},
+		{s.GetWikiPath(fromUser, fromName), s.GetWikiPath(toUser, toName)},
+	}
+	if _, err := os.Stat(moves[0][0]); os.IsNotExist(err) {
+		return ErrRepositoryNotFound
+	} else if err != nil {
+		return err
+	}
+	for _, move := range moves {
+		if _, err := os.Stat(move[1]); err == nil {
+			return ErrRepositoryExists
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.reposPath, toUser), 0755); err != nil {
+		return fmt.Errorf("failed to create owner directory: %w", err)
+	}
+	return moveAll(moves)
+}
+
 func (s *Service) ListBranches(username, repoName string) ([]string, error) {
 	repoPath := s.GetRepositoryPath(username, repoName)
 
@@ -90,3 +125,23 @@ func (s *Service) GetLatestCommit(username, repoName, branch string) (string, er
 
 	return strings.TrimSpace(string(output)), nil
 }
+
+// ResolveCommit resolves a branch, tag or (abbreviated) SHA to a full commit SHA.
+func (s *Service) ResolveCommit(username, repoName, ref string) (string, error) {
+	repoPath := s.GetRepositoryPath(username, repoName)
+
+	cmd := exec.Command("git", "rev-parse", "--verify", "--quiet", "--end-of-options", ref+"^{commit}")
+	cmd.Dir = repoPath
+
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve commit %q: %w", ref, err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// GetSnippetPath returns the bare repository backing a snippet.
+func (s *Service) GetSnippetPath(snippetID uint) string {
+	return filepath.Join(s.reposPath, "snippets", fmt.Sprintf("%d.git", snippetID))
+}
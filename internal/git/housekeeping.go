@@ -0,0 +1,78 @@
+package git
+
+import (
+	"context"
+	"strconv"
+	"strings"
+)
+
+// ObjectStats describes how the objects of a repository are stored.
+type ObjectStats struct {
+	LooseObjects int `json:"loose_objects"`
+	Packs        int `json:"packs"`
+}
+
+// ObjectStats counts the loose objects and packs of a repository.
+func (s *Service) ObjectStats(repoPath string) (ObjectStats, error) {
+	output, err := runGit(repoPath, nil, nil, "count-objects", "-v")
+	if err != nil {
+		return ObjectStats{}, err
+	}
+
+	var stats ObjectStats
+	for _, line := range strings.Split(string(output), "\n") {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			continue
+		}
+		switch key {
+		case "count":
+			stats.LooseObjects = n
+		case "packs":
+			stats.Packs = n
+		}
+	}
+	return stats, nil
+}
+
+// RepackIncremental moves loose objects into a new pack, leaving existing
+// packs alone, then refreshes the multi-pack-index and commit-graph so
+// lookups across the growing set of packs stay fast.
+func (s *Service) RepackIncremental(ctx context.Context, repoPath string) error {
+	if _, err := runGitContext(ctx, repoPath, nil, nil, "repack", "-d", "-l", "--no-write-bitmap-index", "--quiet"); err != nil {
+		return err
+	}
+	return s.writeIndexes(ctx, repoPath)
+}
+
+// GarbageCollect repacks a repository into a single pack with a reachability
+// bitmap and prunes unreachable objects older than two weeks, which is long
+// enough for any push that might still reference them to have finished.
+func (s *Service) GarbageCollect(ctx context.Context, repoPath string) error {
+	env := []string{"GIT_CONFIG_COUNT=1", "GIT_CONFIG_KEY_0=repack.writeBitmaps", "GIT_CONFIG_VALUE_0=true"}
+	if _, err := runGitContext(ctx, repoPath, env, nil, "gc", "--prune=2.weeks.ago", "--quiet"); err != nil {
+		return err
+	}
+	return s.writeIndexes(ctx, repoPath)
+}
+
+func (s *Service) writeIndexes(ctx context.Context, repoPath string) error {
+	if _, err := runGitContext(ctx, repoPath, nil, nil, "commit-graph", "write", "--reachable", "--split"); err != nil {
+		return err
+	}
+
+	stats, err := s.ObjectStats(repoPath)
+	if err != nil {
+		return err
+	}
+	// git refuses to write a multi-pack-index without packs
+	if stats.Packs == 0 {
+		return nil
+	}
+	_, err = runGitContext(ctx, repoPath, nil, nil, "multi-pack-index", "write")
+	return err
+}
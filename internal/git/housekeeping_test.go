@@ -0,0 +1,71 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHousekeeping(t *testing.T) {
+	service, repoPath := newTestRepository(t, map[string]string{"README.md": "hello", "a.txt": "a"})
+	ctx := context.Background()
+
+	stats, err := service.ObjectStats(repoPath)
+	if err != nil {
+		t.Fatalf("ObjectStats failed: %v", err)
+	}
+	if stats.LooseObjects == 0 || stats.Packs != 0 {
+		t.Fatalf("stats of a fresh repository = %+v, want only loose objects", stats)
+	}
+
+	if err := service.RepackIncremental(ctx, repoPath); err != nil {
+		t.Fatalf("RepackIncremental failed: %v", err)
+	}
+	stats, _ = service.ObjectStats(repoPath)
+	if stats.LooseObjects != 0 || stats.Packs != 1 {
+		t.Errorf("stats after incremental repack = %+v, want one pack", stats)
+	}
+	for _, name := range []string{"objects/pack/multi-pack-index", "objects/info/commit-graphs"} {
+		if _, err := os.Stat(filepath.Join(repoPath, name)); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+
+	if _, err := service.CommitFiles(repoPath, CommitOptions{
+		Branch: "main", Message: "more", Author: Signature{Name: "alice", Email: "alice@example.com"},
+		Files: []FileChange{{Path: "b.txt", Content: []byte("b")}},
+	}); err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+	if err := service.RepackIncremental(ctx, repoPath); err != nil {
+		t.Fatalf("RepackIncremental failed: %v", err)
+	}
+	if stats, _ = service.ObjectStats(repoPath); stats.Packs != 2 {
+		t.Errorf("packs after second incremental repack = %d, want 2", stats.Packs)
+	}
+
+	if err := service.GarbageCollect(ctx, repoPath); err != nil {
+		t.Fatalf("GarbageCollect failed: %v", err)
+	}
+	if stats, _ = service.ObjectStats(repoPath); stats.LooseObjects != 0 || stats.Packs != 1 {
+		t.Errorf("stats after gc = %+v, want a single pack", stats)
+	}
+	bitmaps, _ := filepath.Glob(filepath.Join(repoPath, "objects/pack/*.bitmap"))
+	if len(bitmaps) == 0 {
+		t.Errorf("gc did not write a bitmap")
+	}
+	if _, err := service.ResolveCommit("alice", "project", "main"); err != nil {
+		t.Errorf("history lost after gc: %v", err)
+	}
+}
+
+func TestHousekeepingEmptyRepository(t *testing.T) {
+	service, repoPath := newTestRepository(t, nil)
+	if err := service.RepackIncremental(context.Background(), repoPath); err != nil {
+		t.Errorf("RepackIncremental failed: %v", err)
+	}
+	if err := service.GarbageCollect(context.Background(), repoPath); err != nil {
+		t.Errorf("GarbageCollect failed: %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// importRefspecs copies branches and tags, leaving out hosting-specific refs
+// such as merge request heads.
+var importRefspecs = []string{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"}
+
+// ImportFromURL fetches the branches and tags of a remote repository into an
+// empty repository and points HEAD at the remote's default branch.
+func (s *Service) ImportFromURL(ctx context.Context, repoPath, remoteURL string) error {
+	args := append([]string{"fetch", "--no-write-fetch-head", remoteURL}, importRefspecs...)
+	if _, err := runGitContext(ctx, repoPath, s.remoteEnv(), nil, args...); err != nil {
+		return err
+	}
+	return s.followRemoteHead(ctx, repoPath, remoteURL)
+}
+
+// ImportBundle fetches the branches and tags of a git bundle into an empty
+// repository. HEAD points at the branch the bundle's HEAD is on, if it has
+// one, and otherwise stays on main or moves to the first branch.
+func (s *Service) ImportBundle(ctx context.Context, repoPath, bundlePath string) error {
+	if _, err := runGitContext(ctx, repoPath, nil, nil, "bundle", "verify", "--quiet", bundlePath); err != nil {
+		return err
+	}
+
+	args := append([]string{"fetch", "--no-write-fetch-head", bundlePath}, importRefspecs...)
+	if _, err := runGitContext(ctx, repoPath, nil, nil, args...); err != nil {
+		return err
+	}
+
+	output, err := runGitContext(ctx, repoPath, nil, nil, "bundle", "list-heads", bundlePath)
+	if err != nil {
+		return err
+	}
+	var head string
+	branches := map[string][]string{}
+	var names []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		sha, ref, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		if ref == "HEAD" {
+			head = sha
+		} else if branch, ok := strings.CutPrefix(ref, "refs/heads/"); ok {
+			branches[sha] = append(branches[sha], branch)
+			names = append(names, branch)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+
+	// Prefer main when several branches point at HEAD
+	candidates := branches[head]
+	if len(candidates) == 0 {
+		candidates = names
+	}
+	branch := candidates[0]
+	if slices.Contains(candidates, "main") {
+		branch = "main"
+	}
+	return s.SetDefaultBranch(repoPath, branch)
+}
+
+// CreateBundle writes every ref of a repository, and HEAD, to a git bundle.
+// It returns false without writing anything when the repository has no refs,
+// since git cannot create an empty bundle.
+func (s *Service) CreateBundle(ctx context.Context, repoPath, bundlePath string) (bool, error) {
+	refs, err := s.ListRefs(repoPath, "refs/")
+	if err != nil {
+		return false, err
+	}
+	if len(refs) == 0 {
+		return false, nil
+	}
+	if _, err := runGitContext(ctx, repoPath, nil, nil, "bundle", "create", "--quiet", bundlePath, "--all"); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RepositorySize returns the disk space used by a repository in bytes.
+func (s *Service) RepositorySize(repoPath string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(repoPath, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// Files disappear while git repacks or prunes
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,107 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestImportFromURL(t *testing.T) {
+	service, upstream := newTestRepository(t, map[string]string{"README.md": "hello"})
+	if _, err := runGit(upstream, nil, nil, "update-ref", "refs/merge-requests/1/head", "main"); err != nil {
+		t.Fatalf("update-ref failed: %v", err)
+	}
+	if _, err := runGit(upstream, nil, nil, "tag", "v1", "main"); err != nil {
+		t.Fatalf("tag failed: %v", err)
+	}
+	if _, err := runGit(upstream, nil, nil, "branch", "-m", "main", "trunk"); err != nil {
+		t.Fatalf("branch failed: %v", err)
+	}
+
+	if err := service.InitBareRepository("bob", "imported"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	repoPath := service.GetRepositoryPath("bob", "imported")
+	service.AllowFileRemotes(true)
+	if err := service.ImportFromURL(context.Background(), repoPath, "file://"+upstream); err != nil {
+		t.Fatalf("ImportFromURL failed: %v", err)
+	}
+
+	refs, _ := service.ListRefs(repoPath, "refs/")
+	if want := []string{"heads/trunk", "tags/v1"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs = %v, want %v", refs, want)
+	}
+	if head, _ := service.DefaultBranch(repoPath); head != "trunk" {
+		t.Errorf("HEAD = %q, want trunk", head)
+	}
+}
+
+func TestImportBundle(t *testing.T) {
+	service, source := newTestRepository(t, map[string]string{"README.md": "hello"})
+	if _, err := runGit(source, nil, nil, "branch", "dev", "main"); err != nil {
+		t.Fatalf("branch failed: %v", err)
+	}
+	bundlePath := filepath.Join(t.TempDir(), "project.bundle")
+	if _, err := runGit(source, nil, nil, "bundle", "create", bundlePath, "--all"); err != nil {
+		t.Fatalf("bundle create failed: %v", err)
+	}
+
+	if err := service.InitBareRepository("bob", "imported"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	repoPath := service.GetRepositoryPath("bob", "imported")
+	if err := service.ImportBundle(context.Background(), repoPath, bundlePath); err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+
+	branches, _ := service.ListRefs(repoPath, "refs/heads/")
+	if !reflect.DeepEqual(branches, []string{"dev", "main"}) {
+		t.Errorf("branches = %v, want [dev main]", branches)
+	}
+	if head, _ := service.DefaultBranch(repoPath); head != "main" {
+		t.Errorf("HEAD = %q, want main", head)
+	}
+
+	// Anything that is not a bundle is rejected
+	if err := os.WriteFile(bundlePath, []byte("not a bundle"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.ImportBundle(context.Background(), repoPath, bundlePath); err == nil {
+		t.Error("ImportBundle accepted an invalid bundle")
+	}
+}
+
+func TestCreateBundle(t *testing.T) {
+	service, repoPath := newTestRepository(t, map[string]string{"README.md": "hello"})
+	bundlePath := filepath.Join(t.TempDir(), "project.bundle")
+	ok, err := service.CreateBundle(context.Background(), repoPath, bundlePath)
+	if err != nil || !ok {
+		t.Fatalf("CreateBundle = %v, %v, want true", ok, err)
+	}
+
+	if err := service.InitBareRepository("bob", "copy"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	copyPath := service.GetRepositoryPath("bob", "copy")
+	if err := service.ImportBundle(context.Background(), copyPath, bundlePath); err != nil {
+		t.Fatalf("ImportBundle failed: %v", err)
+	}
+	if branches, _ := service.ListRefs(copyPath, "refs/heads/"); !reflect.DeepEqual(branches, []string{"main"}) {
+		t.Errorf("branches = %v, want [main]", branches)
+	}
+
+	// Empty repositories produce no bundle
+	emptyBundle := filepath.Join(t.TempDir(), "empty.bundle")
+	repoPath = service.GetRepositoryPath("bob", "empty")
+	if err := service.InitBareRepositoryAt(repoPath, "main"); err != nil {
+		t.Fatalf("InitBareRepositoryAt failed: %v", err)
+	}
+	if ok, err := service.CreateBundle(context.Background(), repoPath, emptyBundle); err != nil || ok {
+		t.Errorf("CreateBundle of empty repository = %v, %v, want false", ok, err)
+	}
+	if _, err := os.Stat(emptyBundle); !os.IsNotExist(err) {
+		t.Errorf("bundle written for empty repository")
+	}
+}
@@ -0,0 +1,91 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// quarantineDir holds repositories moved aside by an administrator. Like the
+// trash, the leading dot keeps it clear of any username.
+const quarantineDir = ".quarantine"
+
+// RepositoryDir is a bare repository directory found on disk.
+type RepositoryDir struct {
+	// Path is relative to the repositories root, e.g. "alice/project.git"
+	Path    string
+	Owner   string
+	Name    string
+	Wiki    bool
+	ModTime time.Time
+}
+
+// ListRepositoryDirs returns the repository and wiki directories under each
+// owner. The trash, quarantine and snippet directories are not included.
+func (s *Service) ListRepositoryDirs() ([]RepositoryDir, error) {
+	owners, err := os.ReadDir(s.reposPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var dirs []RepositoryDir
+	for _, owner := range owners {
+		if !owner.IsDir() || strings.HasPrefix(owner.Name(), ".") || owner.Name() == "snippets" {
+			continue
+		}
+		entries, err := os.ReadDir(filepath.Join(s.reposPath, owner.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutSuffix(entry.Name(), ".git")
+			if !entry.IsDir() || !ok {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil {
+				continue
+			}
+			dir := RepositoryDir{
+				Path:    owner.Name() + "/" + entry.Name(),
+				Owner:   owner.Name(),
+				ModTime: info.ModTime(),
+			}
+			dir.Name, dir.Wiki = strings.CutSuffix(name, ".wiki")
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs, nil
+}
+
+// Fsck verifies the connectivity and validity of every object in a
+// repository. The error describes what is broken.
+func (s *Service) Fsck(ctx context.Context, repoPath string) error {
+	_, err := runGitContext(ctx, repoPath, nil, nil, "fsck", "--no-dangling", "--no-progress")
+	return err
+}
+
+// Quarantine moves a repository directory, given relative to the
+// repositories root, out of the way into the quarantine directory and
+// returns its new relative path.
+func (s *Service) Quarantine(path string) (string, error) {
+	clean := filepath.Clean(path)
+	if filepath.IsAbs(clean) || strings.HasPrefix(clean, "..") || strings.Count(clean, string(filepath.Separator)) != 1 {
+		return "", fmt.Errorf("invalid repository path %q", path)
+	}
+
+	target := filepath.Join(quarantineDir, fmt.Sprintf("%d-%s", time.Now().Unix(), strings.ReplaceAll(clean, string(filepath.Separator), "-")))
+	if err := os.MkdirAll(filepath.Join(s.reposPath, quarantineDir), 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	if err := os.Rename(filepath.Join(s.reposPath, clean), filepath.Join(s.reposPath, target)); err != nil {
+		return "", fmt.Errorf("failed to quarantine repository: %w", err)
+	}
+	return filepath.ToSlash(target), nil
+}
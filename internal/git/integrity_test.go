@@ -0,0 +1,78 @@
+package git
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListRepositoryDirs(t *testing.T) {
+	service, _ := newTestRepository(t, nil)
+	if err := service.InitBareRepositoryAt(service.GetWikiPath("alice", "project"), "main"); err != nil {
+		t.Fatalf("InitBareRepositoryAt failed: %v", err)
+	}
+	for _, dir := range []string{".trash/1/repo.git", "snippets/1.git", "alice/notes.txt.d"} {
+		if err := os.MkdirAll(filepath.Join(service.reposPath, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs, err := service.ListRepositoryDirs()
+	if err != nil {
+		t.Fatalf("ListRepositoryDirs failed: %v", err)
+	}
+	var got []string
+	for _, dir := range dirs {
+		got = append(got, dir.Owner+"|"+dir.Name+"|"+dir.Path+"|"+map[bool]string{true: "wiki", false: "repo"}[dir.Wiki])
+	}
+	want := "alice|project|alice/project.git|repo,alice|project|alice/project.wiki.git|wiki"
+	if strings.Join(got, ",") != want {
+		t.Errorf("dirs = %v, want %s", got, want)
+	}
+}
+
+func TestFsck(t *testing.T) {
+	service, repoPath := newTestRepository(t, map[string]string{"README.md": "hello"})
+	ctx := context.Background()
+	if err := service.Fsck(ctx, repoPath); err != nil {
+		t.Fatalf("Fsck of a healthy repository failed: %v", err)
+	}
+
+	// Losing an object breaks the history that references it
+	objects, _ := filepath.Glob(filepath.Join(repoPath, "objects", "??", "*"))
+	if len(objects) == 0 {
+		t.Fatal("no loose objects to remove")
+	}
+	for _, object := range objects {
+		os.Remove(object)
+	}
+	if err := service.Fsck(ctx, repoPath); err == nil {
+		t.Error("Fsck of a repository with missing objects succeeded")
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	service, repoPath := newTestRepository(t, nil)
+
+	for _, path := range []string{"../x.git", "/tmp/x.git", "alice", "alice/a/b.git"} {
+		if _, err := service.Quarantine(path); err == nil {
+			t.Errorf("Quarantine(%q) succeeded", path)
+		}
+	}
+
+	moved, err := service.Quarantine("alice/project.git")
+	if err != nil {
+		t.Fatalf("Quarantine failed: %v", err)
+	}
+	if !strings.HasPrefix(moved, ".quarantine/") || !strings.HasSuffix(moved, "-alice-project.git") {
+		t.Errorf("quarantined to %q", moved)
+	}
+	if _, err := os.Stat(repoPath); !os.IsNotExist(err) {
+		t.Errorf("repository still in place: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(service.reposPath, moved, "HEAD")); err != nil {
+		t.Errorf("quarantined repository not found: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is the summary of a single commit.
+type Commit struct {
+	SHA         string    `json:"sha"`
+	AuthorName  string    `json:"author_name"`
+	AuthorEmail string    `json:"author_email"`
+	AuthoredAt  time.Time `json:"authored_at"`
+	Summary     string    `json:"summary"`
+}
+
+// Log returns up to limit commits reachable from ref, newest first. A limit of
+// zero or less returns the full history.
+func (s *Service) Log(repoPath, ref string, limit int) ([]Commit, error) {
+	args := []string{"log", "--format=%H%x00%an%x00%ae%x00%at%x00%s%x1e"}
+	if limit > 0 {
+		args = append(args, "-n", strconv.Itoa(limit))
+	}
+	args = append(args, "--end-of-options", ref, "--")
+
+	output, err := runGit(repoPath, nil, nil, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read log: %w", err)
+	}
+
+	commits := []Commit{}
+	for _, record := range strings.Split(string(output), "\x1e") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		fields := strings.Split(record, "\x00")
+		if len(fields) != 5 {
+			continue
+		}
+		timestamp, _ := strconv.ParseInt(fields[3], 10, 64)
+		commits = append(commits, Commit{
+			SHA:         fields[0],
+			AuthorName:  fields[1],
+			AuthorEmail: fields[2],
+			AuthoredAt:  time.Unix(timestamp, 0).UTC(),
+			Summary:     fields[4],
+		})
+	}
+	return commits, nil
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// mirrorTimeout bounds a single fetch from or push to a remote.
+const mirrorTimeout = 30 * time.Minute
+
+// AllowFileRemotes lets mirrors and imports use file:// URLs, which can read
+// any repository on the server's disk. It is off by default.
+func (s *Service) AllowFileRemotes(allow bool) {
+	s.allowFileRemotes = allow
+}
+
+// remoteEnv keeps git from prompting for credentials and limits it to
+// transports that cannot run arbitrary commands on the server. The file
+// transport is only allowed when file remotes are enabled.
+func (s *Service) remoteEnv() []string {
+	protocols := "http:https"
+	if s.allowFileRemotes {
+		protocols = "file:" + protocols
+	}
+	return []string{"GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL=" + protocols}
+}
+
+// pushEnv is remoteEnv for push mirrors, which never use the file transport.
+var pushEnv = []string{"GIT_TERMINAL_PROMPT=0", "GIT_ALLOW_PROTOCOL=http:https"}
+
+// FetchMirror makes the repository an exact copy of the remote: every ref is
+// fetched, refs deleted upstream are removed and HEAD follows the remote's
+// default branch.
+func (s *Service) FetchMirror(repoPath, remoteURL string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+	defer cancel()
+
+	if _, err := runGitContext(ctx, repoPath, s.remoteEnv(), nil, "fetch", "--prune", "--force", "--no-write-fetch-head", remoteURL, "+refs/*:refs/*"); err != nil {
+		return err
+	}
+	return s.followRemoteHead(ctx, repoPath, remoteURL)
+}
+
+// followRemoteHead points HEAD at the remote's default branch.
+func (s *Service) followRemoteHead(ctx context.Context, repoPath, remoteURL string) error {
+	output, err := runGitContext(ctx, repoPath, s.remoteEnv(), nil, "ls-remote", "--symref", remoteURL, "HEAD")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(output), "\n") {
+		// ref: refs/heads/main<TAB>HEAD
+		if target, ok := strings.CutPrefix(line, "ref: refs/heads/"); ok {
+			branch, _, _ := strings.Cut(target, "\t")
+			return s.SetDefaultBranch(repoPath, branch)
+		}
+	}
+	return nil
+}
+
+// PushMirror pushes the repository to a remote. With no refspecs every ref
+// is mirrored and refs missing locally are deleted on the remote; otherwise
+// only the given refspecs are force-pushed. An empty repository has nothing
+// to push and succeeds without contacting the remote.
+func (s *Service) PushMirror(repoPath, remoteURL string, refspecs []string) error {
+	if err := ValidatePushURL(remoteURL); err != nil {
+		return err
+	}
+	refs, err := s.ListRefs(repoPath, "refs/")
+	if err != nil {
+		return err
+	}
+	if len(refs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+	defer cancel()
+
+	args := []string{"push", "--mirror", remoteURL}
+	if len(refspecs) > 0 {
+		args = append([]string{"push", "--force", remoteURL}, refspecs...)
+	}
+	_, err = runGitContext(ctx, repoPath, pushEnv, nil, args...)
+	return err
+}
+
+// ValidateRemoteURL checks that a mirror or import URL uses a transport git
+// is allowed to fetch from or push to.
+func ValidateRemoteURL(remoteURL string, allowFile bool) error {
+	switch {
+	case strings.HasPrefix(remoteURL, "https://"), strings.HasPrefix(remoteURL, "http://"):
+		return nil
+	case strings.HasPrefix(remoteURL, "file://"):
+		if allowFile {
+			return nil
+		}
+		return fmt.Errorf("file:// URLs are disabled on this server")
+	}
+	return fmt.Errorf("unsupported remote URL %q: use http://, https:// or file://", remoteURL)
+}
+
+// ValidatePushURL checks that a push mirror URL uses http:// or https://.
+// file:// is refused even where mirrors may fetch from it: a mirror push
+// rewrites every ref of its target, which could be another user's
+// repository on this server.
+func ValidatePushURL(remoteURL string) error {
+	if strings.HasPrefix(remoteURL, "file://") {
+		return fmt.Errorf("push mirrors cannot use file:// URLs")
+	}
+	return ValidateRemoteURL(remoteURL, false)
+}
@@ -0,0 +1,145 @@
+package git
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gitlab-tool/internal/githttp"
+)
+
+func TestFetchMirror(t *testing.T) {
+	service, upstream := newTestRepository(t, map[string]string{"README.md": "hello"})
+	author := Signature{Name: "alice", Email: "alice@example.com"}
+	if _, err := service.CommitFiles(upstream, CommitOptions{
+		Branch: "trunk", Message: "trunk", Author: author,
+		Files: []FileChange{{Path: "a.txt", Content: []byte("a")}},
+	}); err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+	if err := service.SetDefaultBranch(upstream, "trunk"); err != nil {
+		t.Fatalf("SetDefaultBranch failed: %v", err)
+	}
+
+	if err := service.InitBareRepository("bob", "mirror"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	mirrorPath := service.GetRepositoryPath("bob", "mirror")
+	if err := service.FetchMirror(mirrorPath, "file://"+upstream); err == nil {
+		t.Fatal("FetchMirror from a file:// URL succeeded with file remotes disabled")
+	}
+	service.AllowFileRemotes(true)
+	if err := service.FetchMirror(mirrorPath, "file://"+upstream); err != nil {
+		t.Fatalf("FetchMirror failed: %v", err)
+	}
+
+	branches, _ := service.ListRefs(mirrorPath, "refs/heads/")
+	if !reflect.DeepEqual(branches, []string{"main", "trunk"}) {
+		t.Errorf("branches = %v, want [main trunk]", branches)
+	}
+	if head, _ := service.DefaultBranch(mirrorPath); head != "trunk" {
+		t.Errorf("HEAD = %q, want trunk", head)
+	}
+
+	// Branches deleted upstream disappear from the mirror
+	if _, err := runGit(upstream, nil, nil, "update-ref", "-d", "refs/heads/main"); err != nil {
+		t.Fatalf("update-ref failed: %v", err)
+	}
+	if err := service.FetchMirror(mirrorPath, "file://"+upstream); err != nil {
+		t.Fatalf("FetchMirror failed: %v", err)
+	}
+	branches, _ = service.ListRefs(mirrorPath, "refs/heads/")
+	if !reflect.DeepEqual(branches, []string{"trunk"}) {
+		t.Errorf("branches after prune = %v, want [trunk]", branches)
+	}
+}
+
+func TestValidateRemoteURL(t *testing.T) {
+	tests := []struct {
+		url       string
+		allowFile bool
+		ok        bool
+	}{
+		{"https://example.com/a.git", false, true},
+		{"http://example.com/a.git", false, true},
+		{"file:///srv/a.git", false, false},
+		{"file:///srv/a.git", true, true},
+		{"ext::sh -c touch% /tmp/x", true, false},
+		{"/srv/a.git", true, false},
+	}
+	for _, tt := range tests {
+		if err := ValidateRemoteURL(tt.url, tt.allowFile); (err == nil) != tt.ok {
+			t.Errorf("ValidateRemoteURL(%q, %v) = %v, want ok %v", tt.url, tt.allowFile, err, tt.ok)
+		}
+	}
+}
+
+// serveRemote serves the bare repository at remotePath over smart HTTP and
+// returns its URL.
+func serveRemote(t *testing.T, remotePath string) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		githttp.Serve(w, r, remotePath, strings.TrimPrefix(r.URL.Path, "/remote.git/"), "")
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/remote.git"
+}
+
+func TestPushMirror(t *testing.T) {
+	service, repoPath := newTestRepository(t, map[string]string{"README.md": "hello"})
+	if _, err := service.CommitFiles(repoPath, CommitOptions{
+		Branch: "feature", Message: "feature", Author: Signature{Name: "alice", Email: "alice@example.com"},
+		Files: []FileChange{{Path: "a.txt", Content: []byte("a")}},
+	}); err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		refspecs []string
+		want     []string
+	}{
+		{"all refs", nil, []string{"feature", "main"}},
+		{"default branch only", []string{"+refs/heads/main:refs/heads/main"}, []string{"main"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := t.TempDir()
+			if err := service.InitBareRepositoryAt(remote, "main"); err != nil {
+				t.Fatalf("InitBareRepositoryAt failed: %v", err)
+			}
+			if err := service.PushMirror(repoPath, serveRemote(t, remote), tt.refspecs); err != nil {
+				t.Fatalf("PushMirror failed: %v", err)
+			}
+			branches, _ := service.ListRefs(remote, "refs/heads/")
+			if !reflect.DeepEqual(branches, tt.want) {
+				t.Errorf("remote branches = %v, want %v", branches, tt.want)
+			}
+		})
+	}
+}
+
+func TestPushMirrorRejectsFileURLs(t *testing.T) {
+	service, repoPath := newTestRepository(t, map[string]string{"README.md": "hello"})
+	service.AllowFileRemotes(true)
+	if err := service.InitBareRepository("bob", "victim"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	victim := service.GetRepositoryPath("bob", "victim")
+
+	for _, url := range []string{"file://" + victim, "file://" + victim + "/../victim.git", victim} {
+		if err := ValidatePushURL(url); err == nil {
+			t.Errorf("ValidatePushURL(%q) succeeded", url)
+		}
+		if err := service.PushMirror(repoPath, url, nil); err == nil {
+			t.Errorf("PushMirror to %q succeeded", url)
+		}
+	}
+	if refs, _ := service.ListRefs(victim, "refs/"); len(refs) != 0 {
+		t.Errorf("refs were pushed to another repository: %v", refs)
+	}
+	if err := ValidatePushURL("https://example.com/a.git"); err != nil {
+		t.Errorf("ValidatePushURL rejected an https URL: %v", err)
+	}
+}
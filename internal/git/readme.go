@@ -0,0 +1,32 @@
+package git
+
+import (
+	"errors"
+	"path"
+	"strings"
+)
+
+var ErrReadmeNotFound = errors.New("readme not found")
+
+// readmeNames lists the README file names we recognise, in order of preference.
+var readmeNames = []string{"readme.md", "readme.txt", "readme"}
+
+// FindReadme looks for a README (case-insensitively) in the repository root
+// and then in docs/ as of ref.
+func (s *Service) FindReadme(repoPath, ref string) (*TreeEntry, error) {
+	for _, dir := range []string{"", "docs"} {
+		entries, err := s.ListDirectory(repoPath, ref, dir)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, name := range readmeNames {
+			for _, entry := range entries {
+				if entry.Type == "blob" && strings.ToLower(path.Base(entry.Path)) == name {
+					return &entry, nil
+				}
+			}
+		}
+	}
+	return nil, ErrReadmeNotFound
+}
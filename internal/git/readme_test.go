@@ -0,0 +1,67 @@
+package git
+
+import (
+	"errors"
+	"testing"
+)
+
+// newTestRepository creates a bare repository with a single commit on main
+// containing files.
+func newTestRepository(t *testing.T, files map[string]string) (*Service, string) {
+	t.Helper()
+	service := NewService(t.TempDir())
+	if err := service.InitBareRepository("alice", "project"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	repoPath := service.GetRepositoryPath("alice", "project")
+
+	changes := []FileChange{}
+	for path, content := range files {
+		changes = append(changes, FileChange{Path: path, Content: []byte(content)})
+	}
+	if len(changes) > 0 {
+		_, err := service.CommitFiles(repoPath, CommitOptions{
+			Branch:  "main",
+			Message: "Initial commit",
+			Author:  Signature{Name: "alice", Email: "alice@example.com"},
+			Files:   changes,
+		})
+		if err != nil {
+			t.Fatalf("CommitFiles failed: %v", err)
+		}
+	}
+	return service, repoPath
+}
+
+func TestFindReadme(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"markdown preferred", map[string]string{"readme": "x", "ReadMe.MD": "x", "README.txt": "x"}, "ReadMe.MD"},
+		{"text before bare", map[string]string{"README": "x", "README.txt": "x"}, "README.txt"},
+		{"docs fallback", map[string]string{"main.go": "x", "docs/README.md": "x"}, "docs/README.md"},
+		{"root before docs", map[string]string{"README": "x", "docs/README.md": "x"}, "README"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, repoPath := newTestRepository(t, tt.files)
+			entry, err := service.FindReadme(repoPath, "main")
+			if err != nil {
+				t.Fatalf("FindReadme failed: %v", err)
+			}
+			if entry.Path != tt.want {
+				t.Errorf("FindReadme = %q, want %q", entry.Path, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindReadmeMissing(t *testing.T) {
+	service, repoPath := newTestRepository(t, map[string]string{"main.go": "package main"})
+	if _, err := service.FindReadme(repoPath, "main"); !errors.Is(err, ErrReadmeNotFound) {
+		t.Errorf("expected ErrReadmeNotFound, got %v", err)
+	}
+}
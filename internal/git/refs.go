@@ -0,0 +1,24 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ListRefs returns the short names of all refs under prefix, e.g.
+// "refs/heads/" for branches or "refs/tags/" for tags.
+func (s *Service) ListRefs(repoPath, prefix string) ([]string, error) {
+	output, err := runGit(repoPath, nil, nil, "for-each-ref", "--format=%(refname)", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %w", err)
+	}
+
+	refs := []string{}
+	for _, line := range strings.Split(string(output), "\n") {
+		if line == "" {
+			continue
+		}
+		refs = append(refs, strings.TrimPrefix(line, prefix))
+	}
+	return refs, nil
+}
@@ -0,0 +1,85 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// trashDir holds deleted repositories until they are restored or purged. The
+// leading dot keeps it clear of any username.
+const trashDir = ".trash"
+
+// GetTrashPath returns the directory a deleted repository is kept in. It
+// holds "repo.git" and, if the repository had one, "wiki.git".
+func (s *Service) GetTrashPath(repositoryID uint) string {
+	return filepath.Join(s.reposPath, trashDir, strconv.FormatUint(uint64(repositoryID), 10))
+}
+
+// TrashRepository moves a repository and its wiki into the trash.
+func (s *Service) TrashRepository(repositoryID uint, username, repoName string) error {
+	trashPath := s.GetTrashPath(repositoryID)
+	// Leftovers from an earlier delete of the same row would block the move
+	if err := os.RemoveAll(trashPath); err != nil {
+		return fmt.Errorf("failed to clear trash directory: %w", err)
+	}
+	if err := os.MkdirAll(trashPath, 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	return moveAll(s.trashMoves(repositoryID, username, repoName))
+}
+
+// RestoreRepository moves a repository and its wiki out of the trash back to
+// username/repoName.
+func (s *Service) RestoreRepository(repositoryID uint, username, repoName string) error {
+	moves := s.trashMoves(repositoryID, username, repoName)
+	for i := range moves {
+		moves[i][0], moves[i][1] = moves[i][1], moves[i][0]
+	}
+	for _, move := range moves {
+		if _, err := os.Stat(move[1]); err == nil {
+			return ErrRepositoryExists
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(s.reposPath, username), 0755); err != nil {
+		return fmt.Errorf("failed to create owner directory: %w", err)
+	}
+	if err := moveAll(moves); err != nil {
+		return err
+	}
+	return os.RemoveAll(s.GetTrashPath(repositoryID))
+}
+
+// PurgeRepository permanently removes a repository from the trash.
+func (s *Service) PurgeRepository(repositoryID uint) error {
+	if err := os.RemoveAll(s.GetTrashPath(repositoryID)); err != nil {
+		return fmt.Errorf("failed to purge repository: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) trashMoves(repositoryID uint, username, repoName string) [][2]string {
+	trashPath := s.GetTrashPath(repositoryID)
+	return [][2]string{
+		{s.GetRepositoryPath(username, repoName), filepath.Join(trashPath, "repo.git")},
+		{s.GetWikiPath(username, repoName), filepath.Join(trashPath, "wiki.git")},
+	}
+}
+
+// moveAll renames each source to its destination, skipping sources that do
+// not exist. If a rename fails the earlier ones are undone.
+func moveAll(moves [][2]string) error {
+	for i, move := range moves {
+		if _, err := os.Stat(move[0]); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.Rename(move[0], move[1]); err != nil {
+			for _, done := range moves[:i] {
+				os.Rename(done[1], done[0])
+			}
+			return fmt.Errorf("failed to move repository: %w", err)
+		}
+	}
+	return nil
+}
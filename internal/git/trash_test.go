@@ -0,0 +1,127 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMoveRepository(t *testing.T) {
+	service, _ := newTestRepository(t, map[string]string{"README.md": "hello"})
+	if err := service.InitBareRepositoryAt(service.GetWikiPath("alice", "project"), "main"); err != nil {
+		t.Fatalf("InitBareRepositoryAt failed: %v", err)
+	}
+
+	if err := service.MoveRepository("alice", "project", "bob", "renamed"); err != nil {
+		t.Fatalf("MoveRepository failed: %v", err)
+	}
+	if !service.RepositoryExists("bob", "renamed") || !service.WikiExists("bob", "renamed") {
+		t.Error("repository or wiki was not moved")
+	}
+	if service.RepositoryExists("alice", "project") {
+		t.Error("repository is still at its old path")
+	}
+
+	// A second move of the same repository, e.g. a concurrent transfer, finds
+	// nothing to move
+	if err := service.MoveRepository("alice", "project", "carol", "project"); !errors.Is(err, ErrRepositoryNotFound) {
+		t.Errorf("moving a missing repository = %v, want ErrRepositoryNotFound", err)
+	}
+	if service.RepositoryExists("carol", "project") {
+		t.Error("moving a missing repository created one")
+	}
+
+	if err := service.InitBareRepository("bob", "taken"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.MoveRepository("bob", "renamed", "bob", "taken"); !errors.Is(err, ErrRepositoryExists) {
+		t.Errorf("moving onto a taken name = %v, want ErrRepositoryExists", err)
+	}
+}
+
+func TestTrashRestorePurge(t *testing.T) {
+	service, _ := newTestRepository(t, map[string]string{"README.md": "hello"})
+	if err := service.InitBareRepositoryAt(service.GetWikiPath("alice", "project"), "main"); err != nil {
+		t.Fatalf("InitBareRepositoryAt failed: %v", err)
+	}
+	trashPath := service.GetTrashPath(1)
+
+	if err := service.TrashRepository(1, "alice", "project"); err != nil {
+		t.Fatalf("TrashRepository failed: %v", err)
+	}
+	if service.RepositoryExists("alice", "project") || service.WikiExists("alice", "project") {
+		t.Error("repository or wiki is still in place after trashing")
+	}
+	for _, name := range []string{"repo.git", "wiki.git"} {
+		if _, err := os.Stat(filepath.Join(trashPath, name)); err != nil {
+			t.Errorf("%s is not in the trash: %v", name, err)
+		}
+	}
+
+	if err := service.RestoreRepository(1, "alice", "project"); err != nil {
+		t.Fatalf("RestoreRepository failed: %v", err)
+	}
+	if !service.RepositoryExists("alice", "project") || !service.WikiExists("alice", "project") {
+		t.Error("repository or wiki was not restored")
+	}
+	if _, err := service.ResolveCommit("alice", "project", "main"); err != nil {
+		t.Errorf("restored repository lost its history: %v", err)
+	}
+	if _, err := os.Stat(trashPath); !os.IsNotExist(err) {
+		t.Errorf("trash directory was left behind: %v", err)
+	}
+
+	// Once trashed again, the name is taken by a new repository
+	if err := service.TrashRepository(1, "alice", "project"); err != nil {
+		t.Fatalf("TrashRepository failed: %v", err)
+	}
+	if err := service.InitBareRepository("alice", "project"); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.RestoreRepository(1, "alice", "project"); !errors.Is(err, ErrRepositoryExists) {
+		t.Errorf("restoring onto a taken name = %v, want ErrRepositoryExists", err)
+	}
+	if _, err := os.Stat(filepath.Join(trashPath, "repo.git")); err != nil {
+		t.Errorf("failed restore did not keep the repository in the trash: %v", err)
+	}
+
+	if err := service.PurgeRepository(1); err != nil {
+		t.Fatalf("PurgeRepository failed: %v", err)
+	}
+	if _, err := os.Stat(trashPath); !os.IsNotExist(err) {
+		t.Errorf("trash directory still exists after purging: %v", err)
+	}
+	if !service.RepositoryExists("alice", "project") {
+		t.Error("purging removed the repository that took the name")
+	}
+}
+
+func TestMoveAllRollback(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, name), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The second destination's parent does not exist, so its rename fails
+	err := moveAll([][2]string{
+		{filepath.Join(dir, "a"), filepath.Join(dir, "a-moved")},
+		{filepath.Join(dir, "missing"), filepath.Join(dir, "skipped")},
+		{filepath.Join(dir, "b"), filepath.Join(dir, "none", "b")},
+	})
+	if err == nil {
+		t.Fatal("moveAll succeeded, want an error")
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not moved back: %v", name, err)
+		}
+	}
+	for _, name := range []string{"a-moved", "skipped"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s exists after the rollback: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TreeEntry is a single file in a tree listing.
+type TreeEntry struct {
+	Mode string `json:"mode"`
+	Type string `json:"type"`
+	SHA  string `json:"sha"`
+	Size int64  `json:"size"`
+	Path string `json:"path"`
+}
+
+// ListTree returns every blob reachable from the tree of ref, recursively.
+func (s *Service) ListTree(repoPath, ref string) ([]TreeEntry, error) {
+	output, err := runGit(repoPath, nil, nil, "ls-tree", "-r", "-l", "-z", "--end-of-options", ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tree: %w", err)
+	}
+	return parseTreeEntries(output), nil
+}
+
+// ListDirectory returns the entries directly inside dir as of ref. An empty
+// dir lists the repository root.
+func (s *Service) ListDirectory(repoPath, ref, dir string) ([]TreeEntry, error) {
+	args := []string{"ls-tree", "-l", "-z", "--end-of-options", ref}
+	if dir != "" {
+		args = append(args, "--", strings.TrimSuffix(dir, "/")+"/")
+	}
+
+	output, err := runGit(repoPath, nil, nil, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list directory: %w", err)
+	}
+	return parseTreeEntries(output), nil
+}
+
+func parseTreeEntries(output []byte) []TreeEntry {
+	entries := []TreeEntry{}
+	for _, record := range bytes.Split(output, []byte{0}) {
+		if len(record) == 0 {
+			continue
+		}
+		// <mode> SP <type> SP <object> SP+ <size> TAB <path>
+		meta, path, found := strings.Cut(string(record), "\t")
+		if !found {
+			continue
+		}
+		fields := strings.Fields(meta)
+		if len(fields) != 4 {
+			continue
+		}
+		// Trees and submodules report "-" as their size
+		size, _ := strconv.ParseInt(fields[3], 10, 64)
+		entries = append(entries, TreeEntry{
+			Mode: fields[0],
+			Type: fields[1],
+			SHA:  fields[2],
+			Size: size,
+			Path: path,
+		})
+	}
+	return entries
+}
+
+// ReadFile returns the contents of path as of ref.
+func (s *Service) ReadFile(repoPath, ref, path string) ([]byte, error) {
+	output, err := runGit(repoPath, nil, nil, "cat-file", "blob", ref+":"+path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return output, nil
+}
+
+// ReadBlob returns the content of the blob with the given SHA.
+func (s *Service) ReadBlob(repoPath, sha string) ([]byte, error) {
+	output, err := runGit(repoPath, nil, nil, "cat-file", "blob", sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob %s: %w", sha, err)
+	}
+	return output, nil
+}
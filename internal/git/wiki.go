@@ -0,0 +1,169 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const wikiBranch = "main"
+
+var (
+	ErrInvalidPageName = errors.New("invalid wiki page name")
+	ErrPageNotFound    = errors.New("wiki page not found")
+	ErrPageExists      = errors.New("wiki page already exists")
+
+	pageNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9 _.-]*$`)
+)
+
+// WikiPage is a markdown page stored in a repository wiki.
+type WikiPage struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	SHA     string `json:"sha"`
+	Size    int64  `json:"size"`
+	Content string `json:"content,omitempty"`
+}
+
+// GetWikiPath returns the bare wiki repository stored next to the main one.
+func (s *Service) GetWikiPath(username, repoName string) string {
+	return filepath.Join(s.reposPath, username, repoName+".wiki.git")
+}
+
+func (s *Service) WikiExists(username, repoName string) bool {
+	_, err := os.Stat(filepath.Join(s.GetWikiPath(username, repoName), "HEAD"))
+	return err == nil
+}
+
+// WikiPagePath maps a page name such as "Getting Started" to the file that
+// stores it ("Getting-Started.md").
+func WikiPagePath(name string) (string, error) {
+	name = strings.TrimSuffix(strings.TrimSpace(name), ".md")
+	if !pageNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return "", ErrInvalidPageName
+	}
+	return strings.ReplaceAll(name, " ", "-") + ".md", nil
+}
+
+func (s *Service) ListWikiPages(username, repoName string) ([]WikiPage, error) {
+	pages := []WikiPage{}
+	if !s.WikiExists(username, repoName) {
+		return pages, nil
+	}
+
+	wikiPath := s.GetWikiPath(username, repoName)
+	entries, err := s.ListTree(wikiPath, "refs/heads/"+wikiBranch)
+	if err != nil {
+		// An empty wiki has no branch yet
+		return pages, nil
+	}
+
+	for _, entry := range entries {
+		if entry.Type != "blob" || !strings.HasSuffix(entry.Path, ".md") {
+			continue
+		}
+		pages = append(pages, WikiPage{
+			Name: strings.TrimSuffix(entry.Path, ".md"),
+			Path: entry.Path,
+			SHA:  entry.SHA,
+			Size: entry.Size,
+		})
+	}
+	return pages, nil
+}
+
+func (s *Service) ReadWikiPage(username, repoName, name string) (*WikiPage, error) {
+	path, err := WikiPagePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, err := s.ListWikiPages(username, repoName)
+	if err != nil {
+		return nil, err
+	}
+	for _, page := range pages {
+		if page.Path != path {
+			continue
+		}
+		content, err := s.ReadFile(s.GetWikiPath(username, repoName), "refs/heads/"+wikiBranch, path)
+		if err != nil {
+			return nil, err
+		}
+		page.Content = string(content)
+		return &page, nil
+	}
+	return nil, ErrPageNotFound
+}
+
+// SaveWikiPage creates or updates a page as a commit by author. When create is
+// true the page must not exist yet, otherwise it must already exist.
+func (s *Service) SaveWikiPage(username, repoName, name, content, message string, author Signature, create bool) (*WikiPage, error) {
+	path, err := WikiPagePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	wikiPath := s.GetWikiPath(username, repoName)
+	if !s.WikiExists(username, repoName) {
+		if err := s.InitBareRepositoryAt(wikiPath, wikiBranch); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = s.ReadWikiPage(username, repoName, name)
+	switch {
+	case create && err == nil:
+		return nil, ErrPageExists
+	case !create && errors.Is(err, ErrPageNotFound):
+		return nil, ErrPageNotFound
+	case err != nil && !errors.Is(err, ErrPageNotFound):
+		return nil, err
+	}
+
+	if message == "" {
+		verb := "Update"
+		if create {
+			verb = "Create"
+		}
+		message = fmt.Sprintf("%s %s", verb, strings.TrimSuffix(path, ".md"))
+	}
+
+	_, err = s.CommitFiles(wikiPath, CommitOptions{
+		Branch:  wikiBranch,
+		Message: message,
+		Author:  author,
+		Files:   []FileChange{{Path: path, Content: []byte(content)}},
+	})
+	if err != nil && !errors.Is(err, ErrNoChanges) {
+		return nil, err
+	}
+
+	return s.ReadWikiPage(username, repoName, name)
+}
+
+func (s *Service) DeleteWikiPage(username, repoName, name, message string, author Signature) error {
+	path, err := WikiPagePath(name)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.ReadWikiPage(username, repoName, name); err != nil {
+		return err
+	}
+
+	if message == "" {
+		message = fmt.Sprintf("Delete %s", strings.TrimSuffix(path, ".md"))
+	}
+
+	_, err = s.CommitFiles(s.GetWikiPath(username, repoName), CommitOptions{
+		Branch:  wikiBranch,
+		Message: message,
+		Author:  author,
+		Files:   []FileChange{{Path: path, Delete: true}},
+	})
+	return err
+}
@@ -0,0 +1,77 @@
+package git
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestWikiPagePath(t *testing.T) {
+	tests := map[string]string{
+		"Home":            "Home.md",
+		"Getting Started": "Getting-Started.md",
+		"setup.md":        "setup.md",
+	}
+	for name, want := range tests {
+		got, err := WikiPagePath(name)
+		if err != nil {
+			t.Errorf("WikiPagePath(%q) failed: %v", name, err)
+		}
+		if got != want {
+			t.Errorf("WikiPagePath(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	for _, name := range []string{"", "../secret", "a/b", ".hidden"} {
+		if _, err := WikiPagePath(name); !errors.Is(err, ErrInvalidPageName) {
+			t.Errorf("WikiPagePath(%q) should be rejected", name)
+		}
+	}
+}
+
+func TestWikiPageLifecycle(t *testing.T) {
+	service := NewService(t.TempDir())
+	author := Signature{Name: "alice", Email: "alice@example.com"}
+
+	pages, err := service.ListWikiPages("alice", "project")
+	if err != nil || len(pages) != 0 {
+		t.Fatalf("expected empty wiki, got %v (%v)", pages, err)
+	}
+
+	if _, err := service.SaveWikiPage("alice", "project", "Home", "# Home", "", author, false); !errors.Is(err, ErrPageNotFound) {
+		t.Errorf("updating a missing page should fail, got %v", err)
+	}
+
+	page, err := service.SaveWikiPage("alice", "project", "Home", "# Home", "", author, true)
+	if err != nil {
+		t.Fatalf("SaveWikiPage failed: %v", err)
+	}
+	if page.Content != "# Home" {
+		t.Errorf("unexpected content %q", page.Content)
+	}
+
+	if _, err := service.SaveWikiPage("alice", "project", "Home", "again", "", author, true); !errors.Is(err, ErrPageExists) {
+		t.Errorf("creating an existing page should fail, got %v", err)
+	}
+
+	if _, err := service.SaveWikiPage("alice", "project", "Home", "# Welcome", "Reword", author, false); err != nil {
+		t.Fatalf("updating page failed: %v", err)
+	}
+
+	wikiPath := service.GetWikiPath("alice", "project")
+	log, err := runGit(wikiPath, nil, nil, "log", "--format=%an <%ae> %s", "main")
+	if err != nil {
+		t.Fatalf("git log failed: %v", err)
+	}
+	want := "alice <alice@example.com> Reword\nalice <alice@example.com> Create Home\n"
+	if string(log) != want {
+		t.Errorf("unexpected history:\n%s", log)
+	}
+
+	if err := service.DeleteWikiPage("alice", "project", "Home", "", author); err != nil {
+		t.Fatalf("DeleteWikiPage failed: %v", err)
+	}
+	pages, _ = service.ListWikiPages("alice", "project")
+	if len(pages) != 0 {
+		t.Errorf("expected no pages after delete, got %v", pages)
+	}
+}
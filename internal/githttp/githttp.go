@@ -0,0 +1,228 @@
+// Package githttp serves git's smart HTTP protocol for bare repositories by
+// running git upload-pack and receive-pack directly, without the
+// git http-backend CGI program. Fetches speak protocol v2 when the client
+// asks for it.
+package githttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Services a client can ask for.
+const (
+	UploadPack  = "git-upload-pack"
+	ReceivePack = "git-receive-pack"
+)
+
+// Service returns the git service an action asks for: the service query
+// parameter of an info/refs request, or the RPC being posted. It returns ""
+// for anything else, including the paths of the dumb protocol.
+func Service(action, query string) string {
+	service := action
+	if action == "info/refs" {
+		service = query
+	}
+	if service == UploadPack || service == ReceivePack {
+		return service
+	}
+	return ""
+}
+
+// Serve handles an info/refs, git-upload-pack or git-receive-pack request
+// for the bare repository at repoPath. remoteUser, if set, is recorded as the
+// committer of ref updates in the reflog. It reports whether git ran to
+// completion; when it did not, the client has been sent an error if the
+// response had not started yet.
+func Serve(w http.ResponseWriter, r *http.Request, repoPath, action, remoteUser string) bool {
+	switch {
+	case action == "info/refs" && r.Method == http.MethodGet:
+		service := Service(action, r.URL.Query().Get("service"))
+		if service == "" {
+			httpError(w, http.StatusForbidden, "Only smart HTTP git clients are supported")
+			return false
+		}
+		return advertise(w, r, repoPath, service)
+	case Service(action, "") != "" && r.Method == http.MethodPost:
+		return rpc(w, r, repoPath, action, remoteUser)
+	case action == "info/refs" || Service(action, "") != "":
+		httpError(w, http.StatusMethodNotAllowed, "Method not allowed")
+		return false
+	default:
+		httpError(w, http.StatusNotFound, "Not found")
+		return false
+	}
+}
+
+// advertise lists the refs and capabilities of a repository, the first
+// request of every fetch and push. Under protocol v2 upload-pack only lists
+// its capabilities and must not be preceded by the service announcement;
+// refs are then requested with ls-refs.
+func advertise(w http.ResponseWriter, r *http.Request, repoPath, service string) bool {
+	var prelude bytes.Buffer
+	if service != UploadPack || protocolVersion(r) < 2 {
+		writePacket(&prelude, "# service="+service+"\n")
+		prelude.WriteString("0000")
+	}
+
+	cmd := command(r, repoPath, service, "--stateless-rpc", "--advertise-refs")
+	return run(w, cmd, "application/x-"+service+"-advertisement", prelude.Bytes())
+}
+
+// rpc runs one round of a fetch negotiation or a push, streaming the
+// request body to git and git's output back to the client.
+func rpc(w http.ResponseWriter, r *http.Request, repoPath, service, remoteUser string) bool {
+	if r.Header.Get("Content-Type") != "application/x-"+service+"-request" {
+		httpError(w, http.StatusUnsupportedMediaType, "Unsupported content type")
+		return false
+	}
+
+	body := io.Reader(r.Body)
+	switch r.Header.Get("Content-Encoding") {
+	case "":
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			httpError(w, http.StatusBadRequest, "Invalid gzip request body")
+			return false
+		}
+		defer reader.Close()
+		body = reader
+	default:
+		httpError(w, http.StatusUnsupportedMediaType, "Unsupported content encoding")
+		return false
+	}
+
+	cmd := command(r, repoPath, service, "--stateless-rpc")
+	cmd.Stdin = body
+	if remoteUser != "" {
+		cmd.Env = append(cmd.Env, "GIT_COMMITTER_NAME="+remoteUser, "GIT_COMMITTER_EMAIL="+remoteUser+"@"+remoteHost(r))
+	}
+	return run(w, cmd, "application/x-"+service+"-result", nil)
+}
+
+// command builds the git invocation for a service. It is bound to the
+// request, so git is killed when the client disconnects. The client's
+// Git-Protocol header is handed to git, which picks the protocol version
+// from it. Partial clone filters are allowed, along with the requests for
+// single reachable objects that partial clones make over protocol v0 when
+// they fetch missing blobs.
+func command(r *http.Request, repoPath, service string, args ...string) *exec.Cmd {
+	args = append([]string{strings.TrimPrefix(service, "git-")}, args...)
+	cmd := exec.CommandContext(r.Context(), "git", append(args, ".")...)
+	cmd.Dir = repoPath
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_COUNT=2",
+		"GIT_CONFIG_KEY_0=uploadpack.allowFilter", "GIT_CONFIG_VALUE_0=true",
+		"GIT_CONFIG_KEY_1=uploadpack.allowReachableSHA1InWant", "GIT_CONFIG_VALUE_1=true",
+	)
+	if protocol := r.Header.Get("Git-Protocol"); protocol != "" {
+		cmd.Env = append(cmd.Env, "GIT_PROTOCOL="+protocol)
+	}
+	return cmd
+}
+
+// protocolVersion returns the highest version=N the Git-Protocol header
+// asks for, the way git reads GIT_PROTOCOL, or 0 if none.
+func protocolVersion(r *http.Request) int {
+	version := 0
+	for _, param := range strings.Split(r.Header.Get("Git-Protocol"), ":") {
+		value, ok := strings.CutPrefix(param, "version=")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.Atoi(value); err == nil && v > version {
+			version = v
+		}
+	}
+	return version
+}
+
+// run streams the output of cmd, preceded by prelude, as the response.
+// Headers are only sent once git has produced output, so a git that fails
+// straight away still gets an error status.
+func run(w http.ResponseWriter, cmd *exec.Cmd, contentType string, prelude []byte) bool {
+	var stderr bytes.Buffer
+	out := &responseWriter{w: w, contentType: contentType, prelude: prelude}
+	cmd.Stdout = out
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if err != nil {
+		fmt.Printf("Warning: git %s failed: %v: %s\n", cmd.Args[1], err, strings.TrimSpace(stderr.String()))
+		if !out.started {
+			httpError(w, http.StatusInternalServerError, "Git operation failed")
+		}
+		return false
+	}
+	out.start()
+	return true
+}
+
+// responseWriter sends the response headers before the first byte of output
+// and flushes after every write so progress reaches the client as it
+// happens.
+type responseWriter struct {
+	w           http.ResponseWriter
+	contentType string
+	prelude     []byte
+	started     bool
+}
+
+func (rw *responseWriter) start() {
+	if rw.started {
+		return
+	}
+	rw.started = true
+	header := rw.w.Header()
+	header.Set("Content-Type", rw.contentType)
+	noCache(header)
+	rw.w.WriteHeader(http.StatusOK)
+	rw.w.Write(rw.prelude)
+}
+
+func (rw *responseWriter) Write(p []byte) (int, error) {
+	rw.start()
+	n, err := rw.w.Write(p)
+	if flusher, ok := rw.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return n, err
+}
+
+// noCache keeps proxies and clients from reusing responses, which describe
+// the state of a repository at one moment.
+func noCache(header http.Header) {
+	header.Set("Expires", "Fri, 01 Jan 1980 00:00:00 GMT")
+	header.Set("Pragma", "no-cache")
+	header.Set("Cache-Control", "no-cache, max-age=0, must-revalidate")
+}
+
+func httpError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	noCache(w.Header())
+	w.WriteHeader(status)
+	io.WriteString(w, message+"\n")
+}
+
+// writePacket writes data as a pkt-line: its length, including the four
+// length bytes, in hex followed by the data.
+func writePacket(w io.Writer, data string) {
+	fmt.Fprintf(w, "%04x%s", len(data)+4, data)
+}
+
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
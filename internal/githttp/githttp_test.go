@@ -0,0 +1,274 @@
+package githttp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab-tool/internal/git"
+)
+
+// newTestServer serves the bare repositories under a temporary root at
+// /<owner>/<name>.git/<action>, with alice/project holding one commit.
+func newTestServer(t *testing.T) (*httptest.Server, *git.Service) {
+	t.Helper()
+	service := git.NewService(t.TempDir())
+	if err := service.InitBareRepository("alice", "project"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	_, err := service.CommitFiles(service.GetRepositoryPath("alice", "project"), git.CommitOptions{
+		Branch:  "main",
+		Message: "Initial commit",
+		Author:  git.Signature{Name: "alice", Email: "alice@example.com"},
+		Files:   []git.FileChange{{Path: "README.md", Content: []byte("hello")}},
+	})
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 3)
+		if len(parts) != 3 {
+			http.NotFound(w, r)
+			return
+		}
+		repoPath := service.GetRepositoryPath(parts[0], strings.TrimSuffix(parts[1], ".git"))
+		Serve(w, r, repoPath, parts[2], "alice")
+	}))
+	t.Cleanup(server.Close)
+	return server, service
+}
+
+// runGit runs the stock git client with a clean configuration.
+func runGit(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GIT_CONFIG_NOSYSTEM=1", "HOME="+t.TempDir(), "GIT_TERMINAL_PROMPT=0",
+		"GIT_AUTHOR_NAME=bob", "GIT_AUTHOR_EMAIL=bob@example.com",
+		"GIT_COMMITTER_NAME=bob", "GIT_COMMITTER_EMAIL=bob@example.com",
+	)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+func TestCloneAndPush(t *testing.T) {
+	server, service := newTestServer(t)
+	url := server.URL + "/alice/project.git"
+	work := filepath.Join(t.TempDir(), "work")
+
+	runGit(t, ".", "clone", "--quiet", url, work)
+	if content, err := os.ReadFile(filepath.Join(work, "README.md")); err != nil || string(content) != "hello" {
+		t.Fatalf("cloned README = %q, %v", content, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(work, "b.txt"), []byte("b"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, work, "add", "b.txt")
+	runGit(t, work, "commit", "--quiet", "-m", "Add b")
+	runGit(t, work, "push", "--quiet", "origin", "main", "main:refs/heads/feature")
+
+	local := strings.TrimSpace(runGit(t, work, "rev-parse", "HEAD"))
+	remote, err := service.ResolveCommit("alice", "project", "feature")
+	if err != nil || remote != local {
+		t.Errorf("pushed feature = %q, %v, want %q", remote, err, local)
+	}
+
+	refs := runGit(t, ".", "ls-remote", url)
+	if !strings.Contains(refs, local+"\trefs/heads/main") || !strings.Contains(refs, local+"\trefs/heads/feature") {
+		t.Errorf("ls-remote = %q", refs)
+	}
+}
+
+func TestAdvertisement(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	resp, err := http.Get(server.URL + "/alice/project.git/info/refs?service=git-upload-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/x-git-upload-pack-advertisement" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "no-cache, max-age=0, must-revalidate" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if !bytes.HasPrefix(body, []byte("001e# service=git-upload-pack\n0000")) {
+		t.Errorf("advertisement starts with %q", body[:min(len(body), 40)])
+	}
+	if !bytes.Contains(body, []byte("refs/heads/main")) {
+		t.Errorf("advertisement does not list main: %q", body)
+	}
+}
+
+func TestGzipRequest(t *testing.T) {
+	server, service := newTestServer(t)
+	head, err := service.ResolveCommit("alice", "project", "main")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var request bytes.Buffer
+	writePacket(&request, "want "+head+"\n")
+	request.WriteString("0000")
+	writePacket(&request, "done\n")
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write(request.Bytes())
+	zw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/alice/project.git/git-upload-pack", &compressed)
+	req.Header.Set("Content-Type", "application/x-git-upload-pack-request")
+	req.Header.Set("Content-Encoding", "gzip")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d: %s", resp.StatusCode, body)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/x-git-upload-pack-result" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !bytes.HasPrefix(body, []byte("0008NAK\n")) || !bytes.Contains(body, []byte("PACK")) {
+		t.Errorf("response is not a pack: %q", body[:min(len(body), 40)])
+	}
+}
+
+func TestProtocolV2(t *testing.T) {
+	server, service := newTestServer(t)
+	url := server.URL + "/alice/project.git"
+	_, err := service.CommitFiles(service.GetRepositoryPath("alice", "project"), git.CommitOptions{
+		Branch:  "feature",
+		Message: "Feature",
+		Author:  git.Signature{Name: "alice", Email: "alice@example.com"},
+		Files:   []git.FileChange{{Path: "feature.txt", Content: []byte("feature")}},
+	})
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+	feature, _ := service.ResolveCommit("alice", "project", "feature")
+
+	refs := runGit(t, ".", "-c", "protocol.version=2", "ls-remote", url, "refs/heads/feature")
+	if refs != feature+"\trefs/heads/feature\n" {
+		t.Errorf("ls-remote = %q", refs)
+	}
+
+	work := filepath.Join(t.TempDir(), "work")
+	runGit(t, ".", "-c", "protocol.version=2", "clone", "--quiet", "--filter=blob:none", url, work)
+	if content, err := os.ReadFile(filepath.Join(work, "README.md")); err != nil || string(content) != "hello" {
+		t.Fatalf("cloned README = %q, %v", content, err)
+	}
+	missing := runGit(t, work, "rev-list", "--objects", "--missing=print", "origin/feature")
+	if !strings.Contains(missing, "\n?") {
+		t.Errorf("clone fetched every blob despite the filter:\n%s", missing)
+	}
+
+	// git falls back to protocol v0 without complaint, so check what went
+	// over the wire. The trace includes the server's side of the
+	// conversation, as upload-pack inherits the environment.
+	trace := filepath.Join(t.TempDir(), "trace")
+	t.Setenv("GIT_TRACE_PACKET", trace)
+	runGit(t, work, "-c", "protocol.version=2", "fetch", "--quiet", "--no-tags", "origin", "refs/heads/feature")
+	t.Setenv("GIT_TRACE_PACKET", "")
+	packets, _ := os.ReadFile(trace)
+	if !bytes.Contains(packets, []byte("upload-pack> version 2")) || !bytes.Contains(packets, []byte("upload-pack< ref-prefix refs/heads/feature")) {
+		t.Errorf("fetch did not use protocol v2 ls-refs:\n%s", packets)
+	}
+	if bytes.Contains(packets, []byte(" refs/heads/main")) {
+		t.Errorf("server listed refs that were not asked for:\n%s", packets)
+	}
+
+	runGit(t, work, "-c", "protocol.version=2", "checkout", "--quiet", "feature")
+	if content, err := os.ReadFile(filepath.Join(work, "feature.txt")); err != nil || string(content) != "feature" {
+		t.Errorf("lazily fetched feature.txt = %q, %v", content, err)
+	}
+}
+
+func TestProtocolV2Requests(t *testing.T) {
+	server, _ := newTestServer(t)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/alice/project.git/info/refs?service=git-upload-pack", nil)
+	req.Header.Set("Git-Protocol", "version=2")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !bytes.HasPrefix(body, []byte("000eversion 2\n")) || !bytes.Contains(body, []byte("ls-refs")) {
+		t.Errorf("v2 advertisement = %q", body)
+	}
+	if bytes.Contains(body, []byte("refs/heads/main")) {
+		t.Errorf("v2 advertisement lists refs: %q", body)
+	}
+
+	var request bytes.Buffer
+	writePacket(&request, "command=ls-refs\n")
+	request.WriteString("0001")
+	writePacket(&request, "ref-prefix refs/tags/\n")
+	request.WriteString("0000")
+	req, _ = http.NewRequest(http.MethodPost, server.URL+"/alice/project.git/git-upload-pack", &request)
+	req.Header.Set("Content-Type", "application/x-git-upload-pack-request")
+	req.Header.Set("Git-Protocol", "version=2")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "0000" {
+		t.Errorf("ls-refs with an unmatched prefix = %d %q, want only a flush", resp.StatusCode, body)
+	}
+}
+
+func TestRejectedRequests(t *testing.T) {
+	server, _ := newTestServer(t)
+	tests := []struct {
+		method, path, contentType string
+		status                    int
+	}{
+		{http.MethodGet, "/alice/project.git/info/refs", "", http.StatusForbidden},
+		{http.MethodGet, "/alice/project.git/info/refs?service=git-archive", "", http.StatusForbidden},
+		{http.MethodGet, "/alice/project.git/HEAD", "", http.StatusNotFound},
+		{http.MethodGet, "/alice/project.git/git-upload-pack", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/alice/project.git/git-upload-pack", "text/plain", http.StatusUnsupportedMediaType},
+		{http.MethodGet, "/alice/missing.git/info/refs?service=git-upload-pack", "", http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, server.URL+tt.path, strings.NewReader(""))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, resp.StatusCode, tt.status)
+		}
+	}
+}
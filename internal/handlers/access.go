@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/middleware"
+	"gitlab-tool/internal/mirror"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// canReadRepository reports whether the user may view a repository.
+func canReadRepository(repo *models.Repository, userID uint) bool {
+	return repo.OwnerID == userID || repo.Visibility != "private"
+}
+
+// canWriteRepository reports whether the user may modify a repository.
+func canWriteRepository(repo *models.Repository, userID uint) bool {
+	return repo.OwnerID == userID
+}
+
+// gitAccess checks that a git client may read a repository, or push to it
+// when write is true, and returns the status to fail the request with, or
+// http.StatusOK. Anonymous clients that need to log in get a challenge so git
+// asks for credentials.
+func gitAccess(c *gin.Context, repo *models.Repository, write bool) int {
+	userID := c.GetUint("user_id")
+	allowed := canReadRepository(repo, userID)
+	if write {
+		allowed = canWriteRepository(repo, userID)
+	}
+	return gitAccessStatus(c, allowed)
+}
+
+// gitAccessStatus turns an access decision for a git client into the status
+// to fail the request with, or http.StatusOK, challenging anonymous clients.
+func gitAccessStatus(c *gin.Context, allowed bool) int {
+	userID := c.GetUint("user_id")
+	switch {
+	case allowed:
+		return http.StatusOK
+	case userID == 0:
+		c.Header("WWW-Authenticate", middleware.GitRealm)
+		return http.StatusUnauthorized
+	default:
+		return http.StatusForbidden
+	}
+}
+
+// rejectArchived writes an error and returns true when the repository is
+// archived, which makes its content read-only.
+func rejectArchived(c *gin.Context, repo *models.Repository) bool {
+	if !repo.Archived {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Repository is archived and read-only"})
+	return true
+}
+
+// loadRepository looks up the repository named by the :id route parameter and
+// checks that the current user can read it. On failure the error response has
+// already been written and ok is false.
+func loadRepository(c *gin.Context, repoRepo *repository.RepositoryRepository) (repo *models.Repository, ok bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return nil, false
+	}
+
+	repo, err = repoRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return nil, false
+	}
+
+	if !canReadRepository(repo, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	return repo, true
+}
+
+// currentSignature builds the commit author for the logged-in user.
+func currentSignature(c *gin.Context, userRepo *repository.UserRepository) (git.Signature, bool) {
+	user, err := userRepo.FindByID(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not found"})
+		return git.Signature{}, false
+	}
+	return git.Signature{Name: user.Username, Email: user.Email}, true
+}
+
+// remoteCredentials validates the URL of a remote repository and separates
+// its credentials from it. Explicit credentials take precedence over ones in
+// the URL. On failure the error response has already been written.
+func remoteCredentials(c *gin.Context, rawURL, username, password string, allowFile bool) (string, string, string, bool) {
+	if err := git.ValidateRemoteURL(rawURL, allowFile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", "", "", false
+	}
+
+	cleanURL, urlUsername, urlPassword, err := mirror.SplitCredentials(rawURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return "", "", "", false
+	}
+	if username == "" && password == "" {
+		username, password = urlUsername, urlPassword
+	}
+
+	return cleanURL, username, password, true
+}
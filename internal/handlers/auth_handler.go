@@ -45,6 +45,12 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 		return
 	}
 
+	// "snippets" is used as the namespace for snippet repositories
+	if req.Username == "snippets" {
+		c.JSON(http.StatusConflict, gin.H{"error": "Username is reserved"})
+		return
+	}
+
 	// Check if username already exists
 	if _, err := h.userRepo.FindByUsername(req.Username); err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"fmt"
+	"html"
+	"net/http"
+	"path"
+	"strings"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/languages"
+	"gitlab-tool/internal/markdown"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BrowseHandler struct {
+	repoRepo        *repository.RepositoryRepository
+	gitService      *git.Service
+	languageService *languages.Service
+	baseURL         string
+	sshHost         string
+}
+
+func NewBrowseHandler(repoRepo *repository.RepositoryRepository, gitService *git.Service, languageService *languages.Service, baseURL, sshHost string) *BrowseHandler {
+	return &BrowseHandler{
+		repoRepo:        repoRepo,
+		gitService:      gitService,
+		languageService: languageService,
+		baseURL:         baseURL,
+		sshHost:         sshHost,
+	}
+}
+
+type ReadmeResponse struct {
+	Path    string `json:"path"`
+	SHA     string `json:"sha"`
+	Size    int64  `json:"size"`
+	Ref     string `json:"ref"`
+	Content string `json:"content"`
+	HTML    string `json:"html"`
+}
+
+type CloneURLs struct {
+	HTTP string `json:"http"`
+	SSH  string `json:"ssh"`
+}
+
+type RepositorySummary struct {
+	Repository    *models.Repository `json:"repository"`
+	DefaultBranch string             `json:"default_branch"`
+	Empty         bool               `json:"empty"`
+	LatestCommit  *git.Commit        `json:"latest_commit"`
+	BranchCount   int                `json:"branch_count"`
+	TagCount      int                `json:"tag_count"`
+	Languages     map[string]int64   `json:"languages"`
+	CloneURLs     CloneURLs          `json:"clone_urls"`
+}
+
+func (h *BrowseHandler) GetReadme(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	repoPath := h.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	ref := h.refOrDefault(c, repoPath)
+
+	entry, err := h.gitService.FindReadme(repoPath, ref)
+	if err != nil {
+		// Unknown refs and empty repositories simply have no README
+		c.JSON(http.StatusNotFound, gin.H{"error": "README not found"})
+		return
+	}
+
+	content, err := h.gitService.ReadFile(repoPath, ref, entry.Path)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read README"})
+		return
+	}
+
+	rendered, err := renderReadme(repo, ref, entry.Path, content)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render README"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ReadmeResponse{
+		Path:    entry.Path,
+		SHA:     entry.SHA,
+		Size:    entry.Size,
+		Ref:     ref,
+		Content: string(content),
+		HTML:    rendered,
+	})
+}
+
+// GetSummary returns everything a repository landing page needs in one call.
+func (h *BrowseHandler) GetSummary(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	repoPath := h.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	summary := RepositorySummary{
+		Repository: repo,
+		Languages:  h.languageBreakdown(repo),
+		CloneURLs:  h.cloneURLs(repo),
+	}
+
+	defaultBranch, err := h.gitService.DefaultBranch(repoPath)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read repository"})
+		return
+	}
+	summary.DefaultBranch = defaultBranch
+
+	commits, err := h.gitService.Log(repoPath, defaultBranch, 1)
+	if err == nil && len(commits) > 0 {
+		summary.LatestCommit = &commits[0]
+	}
+
+	branches, err := h.gitService.ListRefs(repoPath, "refs/heads/")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list branches"})
+		return
+	}
+	tags, err := h.gitService.ListRefs(repoPath, "refs/tags/")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tags"})
+		return
+	}
+	summary.BranchCount = len(branches)
+	summary.TagCount = len(tags)
+	summary.Empty = len(branches) == 0
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// GetLanguages returns the number of bytes per language on the default branch.
+func (h *BrowseHandler) GetLanguages(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, h.languageBreakdown(repo))
+}
+
+// GetBlame annotates a file with the commit that last changed each line.
+// Revisions listed in .git-blame-ignore-revs are skipped unless
+// ?ignore_revs=false is given.
+func (h *BrowseHandler) GetBlame(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	filePath := strings.TrimPrefix(c.Param("path"), "/")
+	if filePath == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File path is required"})
+		return
+	}
+
+	repoPath := h.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	sha, err := h.gitService.ResolveCommit(repo.Owner.Username, repo.Name, c.Param("ref"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ref not found"})
+		return
+	}
+
+	if _, err := h.gitService.ReadFile(repoPath, sha, filePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	ranges, err := h.gitService.Blame(repoPath, sha, filePath, c.DefaultQuery("ignore_revs", "true") != "false")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to blame file"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ref":    c.Param("ref"),
+		"sha":    sha,
+		"path":   filePath,
+		"ranges": ranges,
+	})
+}
+
+// languageBreakdown returns the stored language statistics of a repository.
+// Repositories that have not been analysed yet (e.g. created before language
+// detection existed) are queued so the next request has data. Ones analysed
+// without finding any language are not queued again until the next push.
+func (h *BrowseHandler) languageBreakdown(repo *models.Repository) map[string]int64 {
+	if repo.LanguagesComputedAt == nil {
+		h.languageService.Schedule(repo)
+	}
+
+	breakdown := make(map[string]int64, len(repo.Languages))
+	for _, language := range repo.Languages {
+		breakdown[language.Language] = language.Bytes
+	}
+	return breakdown
+}
+
+// refOrDefault returns the ?ref= query parameter, falling back to the
+// repository's default branch.
+func (h *BrowseHandler) refOrDefault(c *gin.Context, repoPath string) string {
+	if ref := c.Query("ref"); ref != "" {
+		return ref
+	}
+	if branch, err := h.gitService.DefaultBranch(repoPath); err == nil {
+		return branch
+	}
+	return "HEAD"
+}
+
+func (h *BrowseHandler) cloneURLs(repo *models.Repository) CloneURLs {
+	return CloneURLs{
+		HTTP: fmt.Sprintf("%s/git/%s/%s.git", h.baseURL, repo.Owner.Username, repo.Name),
+		SSH:  fmt.Sprintf("git@%s:%s/%s.git", h.sshHost, repo.Owner.Username, repo.Name),
+	}
+}
+
+// renderReadme renders markdown READMEs and shows anything else as
+// preformatted text.
+func renderReadme(repo *models.Repository, ref, filePath string, content []byte) (string, error) {
+	if !strings.EqualFold(path.Ext(filePath), ".md") {
+		return "<pre>" + html.EscapeString(string(content)) + "</pre>", nil
+	}
+
+	dir := path.Dir(filePath)
+	if dir == "." {
+		dir = ""
+	}
+	rendered, err := markdown.Render(content, markdownContext(repo, ref, dir))
+	if err != nil {
+		return "", fmt.Errorf("failed to render README: %w", err)
+	}
+	return rendered, nil
+}
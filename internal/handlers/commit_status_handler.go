@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+	StatusError   = "error"
+)
+
+type CommitStatusHandler struct {
+	statusRepo *repository.CommitStatusRepository
+	repoRepo   *repository.RepositoryRepository
+	gitService *git.Service
+}
+
+func NewCommitStatusHandler(statusRepo *repository.CommitStatusRepository, repoRepo *repository.RepositoryRepository, gitService *git.Service) *CommitStatusHandler {
+	return &CommitStatusHandler{
+		statusRepo: statusRepo,
+		repoRepo:   repoRepo,
+		gitService: gitService,
+	}
+}
+
+type CreateCommitStatusRequest struct {
+	State       string `json:"state" binding:"required,oneof=pending success failure error"`
+	Context     string `json:"context"`
+	Description string `json:"description"`
+	TargetURL   string `json:"target_url" binding:"omitempty,url"`
+}
+
+type CombinedStatusResponse struct {
+	State      string                `json:"state"`
+	SHA        string                `json:"sha"`
+	Ref        string                `json:"ref"`
+	TotalCount int                   `json:"total_count"`
+	Statuses   []models.CommitStatus `json:"statuses"`
+}
+
+func (h *CommitStatusHandler) CreateStatus(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !canWriteRepository(repo, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var req CreateCommitStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sha, err := h.gitService.ResolveCommit(repo.Owner.Username, repo.Name, c.Param("sha"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commit not found"})
+		return
+	}
+
+	if req.Context == "" {
+		req.Context = "default"
+	}
+
+	status := &models.CommitStatus{
+		RepositoryID: repo.ID,
+		SHA:          sha,
+		State:        req.State,
+		Context:      req.Context,
+		Description:  req.Description,
+		TargetURL:    req.TargetURL,
+		CreatorID:    userID,
+	}
+
+	if err := h.statusRepo.Create(status); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create commit status"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, status)
+}
+
+func (h *CommitStatusHandler) ListStatuses(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	sha, err := h.gitService.ResolveCommit(repo.Owner.Username, repo.Name, c.Param("sha"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Commit not found"})
+		return
+	}
+
+	statuses, err := h.statusRepo.FindBySHA(repo.ID, sha)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch commit statuses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+func (h *CommitStatusHandler) GetCombinedStatus(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	ref := c.Param("ref")
+	sha, err := h.gitService.ResolveCommit(repo.Owner.Username, repo.Name, ref)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ref not found"})
+		return
+	}
+
+	statuses, err := h.statusRepo.FindLatestBySHA(repo.ID, sha)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch commit statuses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, CombinedStatusResponse{
+		State:      CombineStatusStates(statuses),
+		SHA:        sha,
+		Ref:        ref,
+		TotalCount: len(statuses),
+		Statuses:   statuses,
+	})
+}
+
+// CombineStatusStates folds the latest status of each context into a single
+// state: any failure or error fails the commit, any pending (or no statuses at
+// all) keeps it pending, and only all-success is success.
+func CombineStatusStates(statuses []models.CommitStatus) string {
+	if len(statuses) == 0 {
+		return StatusPending
+	}
+
+	combined := StatusSuccess
+	for _, status := range statuses {
+		switch status.State {
+		case StatusFailure, StatusError:
+			return StatusFailure
+		case StatusPending:
+			combined = StatusPending
+		}
+	}
+	return combined
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/housekeeping"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HousekeepingHandler struct {
+	repoRepo            *repository.RepositoryRepository
+	housekeepingRepo    *repository.HousekeepingRepository
+	gitService          *git.Service
+	housekeepingService *housekeeping.Service
+}
+
+func NewHousekeepingHandler(repoRepo *repository.RepositoryRepository, housekeepingRepo *repository.HousekeepingRepository, gitService *git.Service, housekeepingService *housekeeping.Service) *HousekeepingHandler {
+	return &HousekeepingHandler{
+		repoRepo:            repoRepo,
+		housekeepingRepo:    housekeepingRepo,
+		gitService:          gitService,
+		housekeepingService: housekeepingService,
+	}
+}
+
+type TriggerHousekeepingRequest struct {
+	Task string `json:"task" binding:"omitempty,oneof=incremental full"`
+}
+
+// GetHousekeeping reports the result of the last maintenance run of a
+// repository and how its objects are currently stored.
+func (h *HousekeepingHandler) GetHousekeeping(c *gin.Context) {
+	repo, ok := h.loadRepository(c)
+	if !ok {
+		return
+	}
+	h.respond(c, http.StatusOK, repo)
+}
+
+// TriggerHousekeeping runs maintenance on a repository in the background. A
+// full run is the default.
+func (h *HousekeepingHandler) TriggerHousekeeping(c *gin.Context) {
+	repo, ok := h.loadRepository(c)
+	if !ok {
+		return
+	}
+
+	var req TriggerHousekeepingRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Task == "" {
+		req.Task = housekeeping.TaskFull
+	}
+
+	h.housekeepingService.Trigger(repo.ID, req.Task)
+	h.respond(c, http.StatusAccepted, repo)
+}
+
+func (h *HousekeepingHandler) respond(c *gin.Context, status int, repo *models.Repository) {
+	record, err := h.housekeepingRepo.Find(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load housekeeping"})
+		return
+	}
+	stats, err := h.gitService.ObjectStats(h.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to inspect repository"})
+		return
+	}
+
+	c.JSON(status, gin.H{"housekeeping": record, "objects": stats})
+}
+
+// loadRepository finds a repository by ID for an administrator, who may not
+// be able to read it otherwise.
+func (h *HousekeepingHandler) loadRepository(c *gin.Context) (*models.Repository, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return nil, false
+	}
+	repo, err := h.repoRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return nil, false
+	}
+	return repo, true
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"strconv"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/imports"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler moves repositories into the server, from existing history or
+// project archives, and exports them as project archives.
+type ImportHandler struct {
+	repoRepo      *repository.RepositoryRepository
+	importRepo    *repository.ImportRepository
+	gitService    *git.Service
+	importService *imports.Service
+	quotaService  *quota.Service
+	allowFile     bool
+}
+
+func NewImportHandler(repoRepo *repository.RepositoryRepository, importRepo *repository.ImportRepository, gitService *git.Service, importService *imports.Service, quotaService *quota.Service, allowFile bool) *ImportHandler {
+	return &ImportHandler{
+		repoRepo:      repoRepo,
+		importRepo:    importRepo,
+		gitService:    gitService,
+		importService: importService,
+		quotaService:  quotaService,
+		allowFile:     allowFile,
+	}
+}
+
+// ImportRepositoryRequest is sent as JSON to import from a URL, or as a
+// multipart form with a "bundle" file to import a git bundle.
+type ImportRepositoryRequest struct {
+	Name        string `json:"name" form:"name" binding:"required"`
+	Description string `json:"description" form:"description"`
+	Visibility  string `json:"visibility" form:"visibility" binding:"oneof=public private"`
+	URL         string `json:"url" form:"url"`
+	Username    string `json:"username" form:"username"`
+	Password    string `json:"password" form:"password"`
+}
+
+// ImportArchiveRequest is sent as a multipart form with an "archive" file.
+// Name and visibility default to the ones in the archive.
+type ImportArchiveRequest struct {
+	Name       string `form:"name"`
+	Visibility string `form:"visibility" binding:"omitempty,oneof=public private"`
+}
+
+// ImportRepository creates a repository and fills it from a remote URL or an
+// uploaded bundle in the background. Progress is reported by GetImport.
+func (h *ImportHandler) ImportRepository(c *gin.Context) {
+	h.limitBody(c)
+
+	var req ImportRepositoryRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.writeBindError(c, err)
+		return
+	}
+
+	bundle, _ := c.FormFile("bundle")
+	if (bundle == nil) == (req.URL == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Provide either a url or a bundle file"})
+		return
+	}
+
+	imp := &models.RepositoryImport{}
+	uploadPath := ""
+	if bundle != nil {
+		var ok bool
+		if uploadPath, ok = h.saveUpload(c, bundle); !ok {
+			return
+		}
+		imp.Source = imports.SourceBundle
+	} else {
+		cleanURL, username, password, ok := remoteCredentials(c, req.URL, req.Username, req.Password, h.allowFile)
+		if !ok {
+			return
+		}
+		imp.Source = imports.SourceURL
+		imp.URL, imp.Username, imp.Password = cleanURL, username, password
+	}
+
+	repo := &models.Repository{
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		OwnerID:     c.GetUint("user_id"),
+	}
+	h.startImport(c, repo, nil, imp, uploadPath)
+}
+
+// ImportArchive recreates a project from an archive made by
+// ExportRepository. The settings are applied right away; the repository,
+// wiki and commit statuses are imported in the background.
+func (h *ImportHandler) ImportArchive(c *gin.Context) {
+	h.limitBody(c)
+
+	var req ImportArchiveRequest
+	if err := c.ShouldBind(&req); err != nil {
+		h.writeBindError(c, err)
+		return
+	}
+
+	archive, err := c.FormFile("archive")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An archive file is required"})
+		return
+	}
+	uploadPath, ok := h.saveUpload(c, archive)
+	if !ok {
+		return
+	}
+
+	manifest, err := imports.ReadManifest(uploadPath)
+	if err != nil {
+		os.Remove(uploadPath)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project := manifest.Project
+	repo := &models.Repository{
+		Name:        project.Name,
+		Description: project.Description,
+		Visibility:  project.Visibility,
+		Topics:      project.Topics,
+		OwnerID:     c.GetUint("user_id"),
+	}
+	settings := func(repo *models.Repository) {
+		repo.Homepage = project.Homepage
+		repo.Archived = project.Archived
+		repo.IsTemplate = project.IsTemplate
+		repo.HasIssues = project.HasIssues
+		repo.HasWiki = project.HasWiki
+		repo.HasMergeRequests = project.HasMergeRequests
+		repo.MergeStrategies = project.MergeStrategies
+	}
+	if req.Name != "" {
+		repo.Name = req.Name
+	}
+	if req.Visibility != "" {
+		repo.Visibility = req.Visibility
+	}
+	if repo.Visibility != "public" {
+		repo.Visibility = "private"
+	}
+
+	h.startImport(c, repo, settings, &models.RepositoryImport{Source: imports.SourceArchive}, uploadPath)
+}
+
+// ExportRepository downloads a project archive of a repository: bundles of
+// the repository and wiki plus its settings and commit statuses.
+func (h *ImportHandler) ExportRepository(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	if !canWriteRepository(repo, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	// Build the archive first so a failure can still be reported
+	file, err := os.CreateTemp("", "export-*.tar.gz")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export repository"})
+		return
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err := h.importService.Export(c.Request.Context(), repo, file); err != nil {
+		fmt.Printf("Warning: Failed to export repository %d: %v\n", repo.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export repository"})
+		return
+	}
+
+	c.FileAttachment(file.Name(), fmt.Sprintf("%s-%s.tar.gz", repo.Owner.Username, repo.Name))
+}
+
+// GetImport reports the status of an import started by the current user.
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	imp, err := h.importRepo.FindByID(uint(id))
+	if err != nil || imp.UserID != c.GetUint("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Import not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, imp)
+}
+
+// startImport creates the repository and import record and schedules the
+// import. settings, if not nil, is applied to the repository once it exists,
+// since creating it replaces false settings with their defaults. The uploaded
+// file is removed if anything fails before the import is scheduled.
+func (h *ImportHandler) startImport(c *gin.Context, repo *models.Repository, settings func(repo *models.Repository), imp *models.RepositoryImport, uploadPath string) {
+	if rejectOverQuota(c, h.quotaService) || !createRepository(c, h.repoRepo, h.gitService, repo) {
+		if uploadPath != "" {
+			os.Remove(uploadPath)
+		}
+		return
+	}
+	if settings != nil {
+		settings(repo)
+		if err := h.repoRepo.Update(repo); err != nil {
+			fmt.Printf("Warning: Failed to save settings of repository %d: %v\n", repo.ID, err)
+		}
+	}
+
+	imp.RepositoryID = &repo.ID
+	imp.UserID = c.GetUint("user_id")
+	imp.Name = repo.Name
+	imp.Status = imports.StatusPending
+	if err := h.importRepo.Create(imp); err != nil {
+		os.RemoveAll(h.gitService.GetRepositoryPath(c.GetString("username"), repo.Name))
+		h.repoRepo.Purge(repo.ID)
+		if uploadPath != "" {
+			os.Remove(uploadPath)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create import"})
+		return
+	}
+	h.importService.Schedule(imp, uploadPath)
+
+	c.JSON(http.StatusAccepted, gin.H{"repository": repo, "import": imp})
+}
+
+// limitBody caps uploads at the import size limit, leaving room for the form
+// fields around the file.
+func (h *ImportHandler) limitBody(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.importService.MaxSize()+1<<20)
+}
+
+func (h *ImportHandler) writeBindError(c *gin.Context, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		h.writeTooLarge(c)
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+func (h *ImportHandler) writeTooLarge(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Upload exceeds the import size limit of %d MB", h.importService.MaxSize()>>20)})
+}
+
+// saveUpload stores an uploaded file in a temporary file and returns its
+// path. On failure the error response has already been written.
+func (h *ImportHandler) saveUpload(c *gin.Context, upload *multipart.FileHeader) (string, bool) {
+	if upload.Size > h.importService.MaxSize() {
+		h.writeTooLarge(c)
+		return "", false
+	}
+
+	file, err := os.CreateTemp("", "import-*")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload"})
+		return "", false
+	}
+	file.Close()
+	if err := c.SaveUploadedFile(upload, file.Name()); err != nil {
+		os.Remove(file.Name())
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload"})
+		return "", false
+	}
+	return file.Name(), true
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gitlab-tool/internal/integrity"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type IntegrityHandler struct {
+	integrityRepo    *repository.IntegrityRepository
+	auditRepo        *repository.AuditRepository
+	integrityService *integrity.Service
+}
+
+func NewIntegrityHandler(integrityRepo *repository.IntegrityRepository, auditRepo *repository.AuditRepository, integrityService *integrity.Service) *IntegrityHandler {
+	return &IntegrityHandler{
+		integrityRepo:    integrityRepo,
+		auditRepo:        auditRepo,
+		integrityService: integrityService,
+	}
+}
+
+// GetReport lists the findings of the last integrity check.
+func (h *IntegrityHandler) GetReport(c *gin.Context) {
+	issues, err := h.integrityRepo.FindAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load integrity issues"})
+		return
+	}
+
+	status := h.integrityService.Status()
+	c.JSON(http.StatusOK, gin.H{"running": status.Running, "checked_at": status.CheckedAt, "issues": issues})
+}
+
+// RunCheck starts an integrity check in the background.
+func (h *IntegrityHandler) RunCheck(c *gin.Context) {
+	h.integrityService.Trigger()
+	c.JSON(http.StatusAccepted, gin.H{"message": "Integrity check started"})
+}
+
+// RepairIssue initializes an empty repository in place of a missing one.
+func (h *IntegrityHandler) RepairIssue(c *gin.Context) {
+	h.resolve(c, "integrity.repaired", h.integrityService.Repair)
+}
+
+// QuarantineIssue moves an orphaned or corrupt repository aside.
+func (h *IntegrityHandler) QuarantineIssue(c *gin.Context) {
+	h.resolve(c, "integrity.quarantined", h.integrityService.Quarantine)
+}
+
+func (h *IntegrityHandler) resolve(c *gin.Context, action string, fix func(*models.IntegrityIssue) error) {
+	id, err := strconv.ParseUint(c.Param("issue_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid issue ID"})
+		return
+	}
+	issue, err := h.integrityRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Issue not found"})
+		return
+	}
+
+	err = fix(issue)
+	if errors.Is(err, integrity.ErrNotApplicable) || errors.Is(err, integrity.ErrResolved) ||
+		errors.Is(err, integrity.ErrStale) || errors.Is(err, integrity.ErrBusy) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to resolve integrity issue %d: %v\n", issue.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve issue"})
+		return
+	}
+
+	event := &models.AuditEvent{
+		ActorID:    c.GetUint("user_id"),
+		Action:     action,
+		TargetType: "integrity_issue",
+		TargetID:   issue.ID,
+		Details:    fmt.Sprintf("%s %s: %s", issue.Kind, issue.Path, issue.Outcome),
+	}
+	if err := h.auditRepo.Create(event); err != nil {
+		fmt.Printf("Warning: Failed to record audit event: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, issue)
+}
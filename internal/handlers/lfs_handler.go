@@ -0,0 +1,537 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab-tool/internal/lfs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// lfsMediaType is the content type of Git LFS API requests and responses.
+const lfsMediaType = "application/vnd.git-lfs+json"
+
+// LFS lock listings are paged; clients may ask for fewer.
+const (
+	defaultLockLimit = 100
+	maxLockLimit     = 1000
+)
+
+// LFSHandler implements the Git LFS batch, basic transfer and locking APIs
+// under /git/:username/:repo.git/info/lfs.
+type LFSHandler struct {
+	repoRepo     *repository.RepositoryRepository
+	lfsRepo      *repository.LFSRepository
+	store        *lfs.Store
+	quotaService *quota.Service
+	baseURL      string
+}
+
+func NewLFSHandler(repoRepo *repository.RepositoryRepository, lfsRepo *repository.LFSRepository, store *lfs.Store, quotaService *quota.Service, baseURL string) *LFSHandler {
+	return &LFSHandler{
+		repoRepo:     repoRepo,
+		lfsRepo:      lfsRepo,
+		store:        store,
+		quotaService: quotaService,
+		baseURL:      baseURL,
+	}
+}
+
+type lfsPointer struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+type lfsBatchRequest struct {
+	Operation string       `json:"operation"`
+	Transfers []string     `json:"transfers"`
+	Objects   []lfsPointer `json:"objects"`
+	HashAlgo  string       `json:"hash_algo"`
+}
+
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header,omitempty"`
+}
+
+type lfsObjectError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lfsBatchObject struct {
+	OID           string               `json:"oid"`
+	Size          int64                `json:"size"`
+	Authenticated bool                 `json:"authenticated,omitempty"`
+	Actions       map[string]lfsAction `json:"actions,omitempty"`
+	Error         *lfsObjectError      `json:"error,omitempty"`
+}
+
+type lfsLockOwner struct {
+	Name string `json:"name"`
+}
+
+type lfsLock struct {
+	ID       string       `json:"id"`
+	Path     string       `json:"path"`
+	LockedAt time.Time    `json:"locked_at"`
+	Owner    lfsLockOwner `json:"owner"`
+}
+
+type lfsLockRequest struct {
+	Path string `json:"path"`
+}
+
+type lfsListRequest struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit"`
+}
+
+type lfsUnlockRequest struct {
+	Force bool `json:"force"`
+}
+
+// ServeLFS routes a Git LFS request. The git routes send every path under
+// info/lfs here.
+func (h *LFSHandler) ServeLFS(c *gin.Context) {
+	repo, ok := h.loadRepository(c)
+	if !ok {
+		return
+	}
+
+	path := strings.TrimPrefix(strings.TrimPrefix(c.Param("action"), "/"), "info/lfs/")
+	method := c.Request.Method
+	switch {
+	case path == "objects/batch" && method == http.MethodPost:
+		h.batch(c, repo)
+	case strings.HasPrefix(path, "objects/") && method == http.MethodPut:
+		h.upload(c, repo, strings.TrimPrefix(path, "objects/"))
+	case strings.HasPrefix(path, "objects/") && method == http.MethodGet:
+		h.download(c, repo, strings.TrimPrefix(path, "objects/"))
+	case path == "locks" && method == http.MethodPost:
+		h.createLock(c, repo)
+	case path == "locks" && method == http.MethodGet:
+		h.listLocks(c, repo)
+	case path == "locks/verify" && method == http.MethodPost:
+		h.verifyLocks(c, repo)
+	case strings.HasPrefix(path, "locks/") && strings.HasSuffix(path, "/unlock") && method == http.MethodPost:
+		h.unlock(c, repo, strings.TrimSuffix(strings.TrimPrefix(path, "locks/"), "/unlock"))
+	default:
+		lfsError(c, http.StatusNotFound, "Not found")
+	}
+}
+
+// GetUsage reports the LFS storage used by a repository.
+func (h *LFSHandler) GetUsage(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	usage, err := h.lfsRepo.Usage(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute LFS usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+func (h *LFSHandler) batch(c *gin.Context, repo *models.Repository) {
+	var req lfsBatchRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil {
+		lfsError(c, http.StatusBadRequest, "Invalid batch request")
+		return
+	}
+	if req.HashAlgo != "" && req.HashAlgo != "sha256" {
+		lfsError(c, http.StatusConflict, "Only sha256 object IDs are supported")
+		return
+	}
+	if len(req.Transfers) > 0 && !slices.Contains(req.Transfers, "basic") {
+		lfsError(c, http.StatusConflict, "Only the basic transfer adapter is supported")
+		return
+	}
+
+	var upload bool
+	switch req.Operation {
+	case "download":
+	case "upload":
+		upload = true
+	default:
+		lfsError(c, http.StatusUnprocessableEntity, "Operation must be download or upload")
+		return
+	}
+	if !h.authorize(c, repo, upload) {
+		return
+	}
+
+	header := map[string]string{}
+	if authorization := c.GetHeader("Authorization"); authorization != "" {
+		header["Authorization"] = authorization
+	}
+
+	var pending int64
+	objects := make([]lfsBatchObject, 0, len(req.Objects))
+	for _, pointer := range req.Objects {
+		object := lfsBatchObject{OID: pointer.OID, Size: pointer.Size, Authenticated: true}
+		if !lfs.ValidOID(pointer.OID) || pointer.Size < 0 {
+			object.Error = &lfsObjectError{Code: http.StatusUnprocessableEntity, Message: "Invalid object"}
+			objects = append(objects, object)
+			continue
+		}
+
+		// Objects are only served to repositories that reference them, so
+		// knowing an object ID is not enough to read it
+		_, linkErr := h.lfsRepo.FindObject(repo.ID, pointer.OID)
+		size, storeErr := h.store.Size(pointer.OID)
+		present := linkErr == nil && storeErr == nil
+		action := lfsAction{Href: h.objectURL(repo, pointer.OID), Header: header}
+
+		switch {
+		case upload && !present:
+			object.Actions = map[string]lfsAction{"upload": action}
+			pending += pointer.Size
+		case upload:
+			// Already stored; the client has nothing to send
+		case present:
+			object.Size = size
+			object.Actions = map[string]lfsAction{"download": action}
+		default:
+			object.Error = &lfsObjectError{Code: http.StatusNotFound, Message: "Object does not exist"}
+		}
+		objects = append(objects, object)
+	}
+
+	if pending > 0 && !h.checkQuota(c, repo, pending) {
+		return
+	}
+
+	lfsJSON(c, http.StatusOK, gin.H{"transfer": "basic", "objects": objects, "hash_algo": "sha256"})
+}
+
+// upload stores an object sent by the basic transfer adapter. The content
+// must hash to the object ID in the URL.
+func (h *LFSHandler) upload(c *gin.Context, repo *models.Repository, oid string) {
+	if !h.authorize(c, repo, true) {
+		return
+	}
+	if !lfs.ValidOID(oid) {
+		lfsError(c, http.StatusUnprocessableEntity, "Invalid object ID")
+		return
+	}
+
+	// Objects new to the repository count towards its limits. Chunked
+	// uploads do not declare their size, so the body is cut off once it
+	// outgrows the room left.
+	body := io.Reader(c.Request.Body)
+	if _, err := h.lfsRepo.FindObject(repo.ID, oid); err != nil {
+		if !h.checkQuota(c, repo, max(c.Request.ContentLength, 1)) {
+			return
+		}
+		if remaining, limited, err := h.quotaService.Remaining(repo); err != nil {
+			fmt.Printf("Warning: Failed to check storage quota of repository %d: %v\n", repo.ID, err)
+		} else if limited {
+			body = http.MaxBytesReader(c.Writer, c.Request.Body, remaining)
+		}
+	}
+
+	size, err := h.store.Put(oid, body)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		lfsError(c, http.StatusInsufficientStorage, fmt.Sprintf("Repository %s/%s cannot store this object: its storage limit would be exceeded", repo.Owner.Username, repo.Name))
+		return
+	}
+	if errors.Is(err, lfs.ErrHashMismatch) {
+		lfsError(c, http.StatusUnprocessableEntity, "Content does not match the object ID")
+		return
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to store LFS object %s: %v\n", oid, err)
+		lfsError(c, http.StatusInternalServerError, "Failed to store object")
+		return
+	}
+	if c.Request.ContentLength >= 0 && size != c.Request.ContentLength {
+		lfsError(c, http.StatusBadRequest, "Content does not match its declared length")
+		return
+	}
+	if err := h.lfsRepo.LinkObject(repo.ID, oid, size); err != nil {
+		lfsError(c, http.StatusInternalServerError, "Failed to record object")
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// checkQuota rejects storing size more bytes in a repository that would take
+// it or its owner past a storage limit.
+func (h *LFSHandler) checkQuota(c *gin.Context, repo *models.Repository, size int64) bool {
+	var exceeded *quota.ExceededError
+	err := h.quotaService.Check(repo, size)
+	if errors.As(err, &exceeded) {
+		lfsError(c, http.StatusInsufficientStorage, fmt.Sprintf("Repository %s/%s cannot store these objects: %s", repo.Owner.Username, repo.Name, exceeded))
+		return false
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to check storage quota of repository %d: %v\n", repo.ID, err)
+	}
+	return true
+}
+
+func (h *LFSHandler) download(c *gin.Context, repo *models.Repository, oid string) {
+	if !h.authorize(c, repo, false) {
+		return
+	}
+	if !lfs.ValidOID(oid) {
+		lfsError(c, http.StatusUnprocessableEntity, "Invalid object ID")
+		return
+	}
+	if _, err := h.lfsRepo.FindObject(repo.ID, oid); err != nil {
+		lfsError(c, http.StatusNotFound, "Object does not exist")
+		return
+	}
+
+	file, err := h.store.Open(oid)
+	if err != nil {
+		lfsError(c, http.StatusNotFound, "Object does not exist")
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		lfsError(c, http.StatusInternalServerError, "Failed to read object")
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size(), "application/octet-stream", file, nil)
+}
+
+func (h *LFSHandler) createLock(c *gin.Context, repo *models.Repository) {
+	if !h.authorize(c, repo, true) {
+		return
+	}
+
+	var req lfsLockRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil || req.Path == "" {
+		lfsError(c, http.StatusBadRequest, "A path is required")
+		return
+	}
+
+	if existing, err := h.lfsRepo.FindLockByPath(repo.ID, req.Path); err == nil {
+		lfsJSON(c, http.StatusConflict, gin.H{"lock": toLFSLock(existing), "message": "already created lock"})
+		return
+	}
+
+	lock := &models.LFSLock{RepositoryID: repo.ID, Path: req.Path, OwnerID: c.GetUint("user_id")}
+	if err := h.lfsRepo.CreateLock(lock); err != nil {
+		// Lost a race with another client locking the same path
+		if existing, findErr := h.lfsRepo.FindLockByPath(repo.ID, req.Path); findErr == nil {
+			lfsJSON(c, http.StatusConflict, gin.H{"lock": toLFSLock(existing), "message": "already created lock"})
+			return
+		}
+		lfsError(c, http.StatusInternalServerError, "Failed to create lock")
+		return
+	}
+	lock.Owner.Username = c.GetString("username")
+
+	lfsJSON(c, http.StatusCreated, gin.H{"lock": toLFSLock(lock)})
+}
+
+func (h *LFSHandler) listLocks(c *gin.Context, repo *models.Repository) {
+	if !h.authorize(c, repo, false) {
+		return
+	}
+
+	search := repository.LockSearch{Path: c.Query("path")}
+	if id := c.Query("id"); id != "" {
+		parsed, err := strconv.ParseUint(id, 10, 32)
+		if err != nil {
+			lfsJSON(c, http.StatusOK, gin.H{"locks": []lfsLock{}})
+			return
+		}
+		search.ID = uint(parsed)
+	}
+
+	locks, next, ok := h.findLocks(c, repo, search, c.Query("cursor"), c.Query("limit"))
+	if !ok {
+		return
+	}
+	response := gin.H{"locks": locks}
+	if next != "" {
+		response["next_cursor"] = next
+	}
+	lfsJSON(c, http.StatusOK, response)
+}
+
+// verifyLocks lists the locks of a repository split into the caller's and
+// everybody else's, which git-lfs checks before pushing.
+func (h *LFSHandler) verifyLocks(c *gin.Context, repo *models.Repository) {
+	if !h.authorize(c, repo, true) {
+		return
+	}
+
+	var req lfsListRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil && err != io.EOF {
+		lfsError(c, http.StatusBadRequest, "Invalid verify request")
+		return
+	}
+
+	locks, next, ok := h.findLocks(c, repo, repository.LockSearch{}, req.Cursor, strconv.Itoa(req.Limit))
+	if !ok {
+		return
+	}
+	ours, theirs := []lfsLock{}, []lfsLock{}
+	for _, lock := range locks {
+		if lock.Owner.Name == c.GetString("username") {
+			ours = append(ours, lock)
+		} else {
+			theirs = append(theirs, lock)
+		}
+	}
+	response := gin.H{"ours": ours, "theirs": theirs}
+	if next != "" {
+		response["next_cursor"] = next
+	}
+	lfsJSON(c, http.StatusOK, response)
+}
+
+// unlock removes a lock. Only its owner can remove it, unless force is set
+// by someone who can write to the repository.
+func (h *LFSHandler) unlock(c *gin.Context, repo *models.Repository, rawID string) {
+	if !h.authorize(c, repo, true) {
+		return
+	}
+
+	var req lfsUnlockRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&req); err != nil && err != io.EOF {
+		lfsError(c, http.StatusBadRequest, "Invalid unlock request")
+		return
+	}
+
+	id, err := strconv.ParseUint(rawID, 10, 32)
+	if err != nil {
+		lfsError(c, http.StatusNotFound, "Lock not found")
+		return
+	}
+	lock, err := h.lfsRepo.FindLockByID(repo.ID, uint(id))
+	if err != nil {
+		lfsError(c, http.StatusNotFound, "Lock not found")
+		return
+	}
+	if lock.OwnerID != c.GetUint("user_id") && !req.Force {
+		lfsError(c, http.StatusForbidden, "Lock is owned by another user; use force to remove it")
+		return
+	}
+
+	if err := h.lfsRepo.DeleteLock(lock.ID); err != nil {
+		lfsError(c, http.StatusInternalServerError, "Failed to remove lock")
+		return
+	}
+	lfsJSON(c, http.StatusOK, gin.H{"lock": toLFSLock(lock)})
+}
+
+// findLocks returns one page of locks and the cursor of the next page, if
+// there is one.
+func (h *LFSHandler) findLocks(c *gin.Context, repo *models.Repository, search repository.LockSearch, cursor, rawLimit string) ([]lfsLock, string, bool) {
+	limit, err := strconv.Atoi(rawLimit)
+	if err != nil || limit <= 0 {
+		limit = defaultLockLimit
+	}
+	limit = min(limit, maxLockLimit)
+	if cursor != "" {
+		afterID, err := strconv.ParseUint(cursor, 10, 32)
+		if err != nil {
+			lfsError(c, http.StatusBadRequest, "Invalid cursor")
+			return nil, "", false
+		}
+		search.AfterID = uint(afterID)
+	}
+
+	// Fetch one extra lock to learn whether there is another page
+	search.Limit = limit + 1
+	found, err := h.lfsRepo.FindLocks(repo.ID, search)
+	if err != nil {
+		lfsError(c, http.StatusInternalServerError, "Failed to list locks")
+		return nil, "", false
+	}
+
+	next := ""
+	if len(found) > limit {
+		found = found[:limit]
+		next = strconv.FormatUint(uint64(found[limit-1].ID), 10)
+	}
+	locks := make([]lfsLock, 0, len(found))
+	for i := range found {
+		locks = append(locks, toLFSLock(&found[i]))
+	}
+	return locks, next, true
+}
+
+// loadRepository resolves the repository in the LFS URL, following renames.
+func (h *LFSHandler) loadRepository(c *gin.Context) (*models.Repository, bool) {
+	username := c.Param("username")
+	repoName := strings.TrimSuffix(c.Param("repo"), ".git")
+
+	repo, err := h.repoRepo.FindByUsernameAndName(username, repoName)
+	if err != nil {
+		repo, err = h.repoRepo.FindByRedirect(username, repoName)
+	}
+	if err != nil {
+		lfsError(c, http.StatusNotFound, "Repository not found")
+		return nil, false
+	}
+	return repo, true
+}
+
+// authorize checks access like the git endpoint does. Writes are also
+// rejected for repositories that cannot be pushed to.
+func (h *LFSHandler) authorize(c *gin.Context, repo *models.Repository, write bool) bool {
+	if status := gitAccess(c, repo, write); status != http.StatusOK {
+		lfsError(c, status, http.StatusText(status))
+		return false
+	}
+	if write && repo.Archived {
+		lfsError(c, http.StatusForbidden, "Repository is archived and read-only")
+		return false
+	}
+	if write && repo.IsMirror {
+		lfsError(c, http.StatusForbidden, "Repository is a pull mirror and cannot be pushed to")
+		return false
+	}
+	return true
+}
+
+func (h *LFSHandler) objectURL(repo *models.Repository, oid string) string {
+	return fmt.Sprintf("%s/git/%s/%s.git/info/lfs/objects/%s", h.baseURL, repo.Owner.Username, repo.Name, oid)
+}
+
+func toLFSLock(lock *models.LFSLock) lfsLock {
+	return lfsLock{
+		ID:       strconv.FormatUint(uint64(lock.ID), 10),
+		Path:     lock.Path,
+		LockedAt: lock.CreatedAt,
+		Owner:    lfsLockOwner{Name: lock.Owner.Username},
+	}
+}
+
+func lfsJSON(c *gin.Context, status int, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(status, lfsMediaType, data)
+}
+
+func lfsError(c *gin.Context, status int, message string) {
+	lfsJSON(c, status, gin.H{"message": message})
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"net/http"
+
+	"gitlab-tool/internal/markdown"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MarkdownHandler struct {
+	repoRepo *repository.RepositoryRepository
+}
+
+func NewMarkdownHandler(repoRepo *repository.RepositoryRepository) *MarkdownHandler {
+	return &MarkdownHandler{repoRepo: repoRepo}
+}
+
+type RenderMarkdownRequest struct {
+	Text string `json:"text" binding:"required"`
+	// Optional repository context for relative links and references
+	RepoID uint   `json:"repo_id"`
+	Ref    string `json:"ref"`
+	Path   string `json:"path"`
+	Wiki   bool   `json:"wiki"`
+}
+
+func (h *MarkdownHandler) Render(c *gin.Context) {
+	var req RenderMarkdownRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var ctx *markdown.Context
+	if req.RepoID != 0 {
+		repo, err := h.repoRepo.FindByID(req.RepoID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+			return
+		}
+		if !canReadRepository(repo, c.GetUint("user_id")) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		ctx = markdownContext(repo, req.Ref, req.Path)
+		ctx.Wiki = req.Wiki
+	}
+
+	html, err := markdown.Render([]byte(req.Text), ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render markdown"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"html": html})
+}
+
+// markdownContext describes a document at dir on ref of repo.
+func markdownContext(repo *models.Repository, ref, dir string) *markdown.Context {
+	return &markdown.Context{
+		Owner: repo.Owner.Username,
+		Repo:  repo.Name,
+		Ref:   ref,
+		Path:  dir,
+	}
+}
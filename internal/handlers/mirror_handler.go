@@ -0,0 +1,298 @@
+package handlers
+
+import (
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/mirror"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MirrorHandler manages pull mirrors, repositories that follow an upstream
+// repository and cannot be pushed to, and push mirrors, remotes a repository
+// is replicated to after every push.
+type MirrorHandler struct {
+	repoRepo        *repository.RepositoryRepository
+	mirrorRepo      *repository.MirrorRepository
+	gitService      *git.Service
+	mirrorService   *mirror.Service
+	quotaService    *quota.Service
+	defaultInterval time.Duration
+	allowFile       bool
+}
+
+func NewMirrorHandler(repoRepo *repository.RepositoryRepository, mirrorRepo *repository.MirrorRepository, gitService *git.Service, mirrorService *mirror.Service, quotaService *quota.Service, defaultInterval time.Duration, allowFile bool) *MirrorHandler {
+	return &MirrorHandler{
+		repoRepo:        repoRepo,
+		mirrorRepo:      mirrorRepo,
+		gitService:      gitService,
+		mirrorService:   mirrorService,
+		quotaService:    quotaService,
+		defaultInterval: defaultInterval,
+		allowFile:       allowFile,
+	}
+}
+
+type CreateMirrorRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility" binding:"oneof=public private"`
+	// URL of the upstream repository. Credentials may be given in the URL or
+	// separately; either way they are never returned by the API.
+	URL             string `json:"url" binding:"required"`
+	Username        string `json:"username"`
+	Password        string `json:"password"`
+	IntervalMinutes int    `json:"interval_minutes" binding:"omitempty,min=1"`
+}
+
+// CreateMirror creates a repository that mirrors an upstream repository.
+// The initial fetch runs in the background; its outcome is reported by
+// GetMirror.
+func (h *MirrorHandler) CreateMirror(c *gin.Context) {
+	var req CreateMirrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	cleanURL, username, password, ok := remoteCredentials(c, req.URL, req.Username, req.Password, h.allowFile)
+	if !ok {
+		return
+	}
+
+	if rejectOverQuota(c, h.quotaService) {
+		return
+	}
+
+	interval := req.IntervalMinutes
+	if interval == 0 {
+		interval = max(int(h.defaultInterval/time.Minute), 1)
+	}
+
+	repo := &models.Repository{
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		OwnerID:     c.GetUint("user_id"),
+		IsMirror:    true,
+	}
+	if !createRepository(c, h.repoRepo, h.gitService, repo) {
+		return
+	}
+
+	pull := &models.PullMirror{
+		RepositoryID:    repo.ID,
+		URL:             cleanURL,
+		Username:        username,
+		Password:        password,
+		IntervalMinutes: interval,
+		LastStatus:      mirror.StatusPending,
+	}
+	if err := h.mirrorRepo.CreatePull(pull); err != nil {
+		// A mirror repository without its mirror can never be synced or
+		// pushed to, so remove it again
+		os.RemoveAll(h.gitService.GetRepositoryPath(c.GetString("username"), repo.Name))
+		h.repoRepo.Purge(repo.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create mirror"})
+		return
+	}
+	h.mirrorService.Schedule(pull)
+
+	c.JSON(http.StatusCreated, gin.H{"repository": repo, "mirror": pull})
+}
+
+func (h *MirrorHandler) GetMirror(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	pull, err := h.mirrorRepo.FindPullByRepositoryID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository is not a mirror"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pull)
+}
+
+// SyncMirror fetches from the upstream now instead of waiting for the next
+// scheduled sync.
+func (h *MirrorHandler) SyncMirror(c *gin.Context) {
+	pull, ok := h.loadOwnPullMirror(c)
+	if !ok {
+		return
+	}
+
+	h.mirrorService.Schedule(pull)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Mirror sync scheduled"})
+}
+
+// DeleteMirror stops mirroring and turns the repository into a regular one
+// that accepts pushes. Its content is kept.
+func (h *MirrorHandler) DeleteMirror(c *gin.Context) {
+	pull, ok := h.loadOwnPullMirror(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mirrorRepo.DeletePull(pull.RepositoryID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete mirror"})
+		return
+	}
+
+	repo := &pull.Repository
+	repo.IsMirror = false
+	if err := h.repoRepo.Update(repo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mirroring stopped"})
+}
+
+type CreatePushMirrorRequest struct {
+	URL                   string `json:"url" binding:"required"`
+	Username              string `json:"username"`
+	Password              string `json:"password"`
+	OnlyProtectedBranches bool   `json:"only_protected_branches"`
+}
+
+func (h *MirrorHandler) ListPushMirrors(c *gin.Context) {
+	repo, ok := h.loadOwnRepository(c)
+	if !ok {
+		return
+	}
+
+	mirrors, err := h.mirrorRepo.FindPushByRepositoryID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch push mirrors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, mirrors)
+}
+
+// CreatePushMirror adds a remote that the repository is pushed to after
+// every push. The first push happens right away.
+func (h *MirrorHandler) CreatePushMirror(c *gin.Context) {
+	repo, ok := h.loadOwnRepository(c)
+	if !ok {
+		return
+	}
+
+	var req CreatePushMirrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := git.ValidatePushURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	cleanURL, username, password, ok := remoteCredentials(c, req.URL, req.Username, req.Password, false)
+	if !ok {
+		return
+	}
+
+	push := &models.PushMirror{
+		RepositoryID:          repo.ID,
+		URL:                   cleanURL,
+		Username:              username,
+		Password:              password,
+		OnlyProtectedBranches: req.OnlyProtectedBranches,
+		LastStatus:            mirror.StatusPending,
+	}
+	if err := h.mirrorRepo.CreatePush(push); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create push mirror"})
+		return
+	}
+	h.mirrorService.SchedulePush(push)
+
+	c.JSON(http.StatusCreated, push)
+}
+
+// SyncPushMirror pushes to one mirror now.
+func (h *MirrorHandler) SyncPushMirror(c *gin.Context) {
+	push, ok := h.loadOwnPushMirror(c)
+	if !ok {
+		return
+	}
+
+	h.mirrorService.SchedulePush(push)
+	c.JSON(http.StatusAccepted, gin.H{"message": "Push mirror sync scheduled"})
+}
+
+func (h *MirrorHandler) DeletePushMirror(c *gin.Context) {
+	push, ok := h.loadOwnPushMirror(c)
+	if !ok {
+		return
+	}
+
+	if err := h.mirrorRepo.DeletePush(push.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete push mirror"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Push mirror deleted"})
+}
+
+// loadOwnRepository loads the :id repository and checks that the current
+// user owns it, since mirror settings point at private infrastructure.
+func (h *MirrorHandler) loadOwnRepository(c *gin.Context) (*models.Repository, bool) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return nil, false
+	}
+
+	if !canWriteRepository(repo, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	return repo, true
+}
+
+func (h *MirrorHandler) loadOwnPushMirror(c *gin.Context) (*models.PushMirror, bool) {
+	repo, ok := h.loadOwnRepository(c)
+	if !ok {
+		return nil, false
+	}
+
+	mirrorID, err := strconv.ParseUint(c.Param("mirror_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mirror ID"})
+		return nil, false
+	}
+
+	push, err := h.mirrorRepo.FindPushByID(uint(mirrorID))
+	if err != nil || push.RepositoryID != repo.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Push mirror not found"})
+		return nil, false
+	}
+
+	return push, true
+}
+
+func (h *MirrorHandler) loadOwnPullMirror(c *gin.Context) (*models.PullMirror, bool) {
+	repo, ok := h.loadOwnRepository(c)
+	if !ok {
+		return nil, false
+	}
+
+	pull, err := h.mirrorRepo.FindPullByRepositoryID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository is not a mirror"})
+		return nil, false
+	}
+
+	return pull, true
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPerPage = 20
+	maxPerPage     = 100
+)
+
+// Pagination holds the page/per_page query parameters shared by all list
+// endpoints.
+type Pagination struct {
+	Page    int
+	PerPage int
+}
+
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PerPage
+}
+
+// parsePagination reads ?page= and ?per_page=, clamping them to sane values.
+func parsePagination(c *gin.Context) Pagination {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("per_page"))
+	if err != nil || perPage < 1 {
+		perPage = defaultPerPage
+	}
+	if perPage > maxPerPage {
+		perPage = maxPerPage
+	}
+	return Pagination{Page: page, PerPage: perPage}
+}
+
+// writePage sets the X-Total-Count and RFC 8288 Link headers and responds with
+// the items of one page.
+func writePage(c *gin.Context, p Pagination, total int64, items interface{}) {
+	lastPage := int((total + int64(p.PerPage) - 1) / int64(p.PerPage))
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	links := []string{}
+	addLink := func(page int, rel string) {
+		query := c.Request.URL.Query()
+		query.Set("page", strconv.Itoa(page))
+		query.Set("per_page", strconv.Itoa(p.PerPage))
+		u := *c.Request.URL
+		u.RawQuery = query.Encode()
+		links = append(links, fmt.Sprintf(`<%s>; rel="%s"`, u.RequestURI(), rel))
+	}
+	if p.Page > 1 {
+		addLink(1, "first")
+		addLink(min(p.Page-1, lastPage), "prev")
+	}
+	if p.Page < lastPage {
+		addLink(p.Page+1, "next")
+		addLink(lastPage, "last")
+	}
+
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.JSON(http.StatusOK, gin.H{
+		"total_count": total,
+		"page":        p.Page,
+		"per_page":    p.PerPage,
+		"items":       items,
+	})
+}
@@ -1,33 +1,46 @@
 package handlers
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/exec"
-	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/githttp"
+	"gitlab-tool/internal/jobs"
 	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
 	"gitlab-tool/internal/repository"
+	"gitlab-tool/internal/templates"
 
 	"github.com/gin-gonic/gin"
 )
 
 type RepositoryHandler struct {
-	repoRepo   *repository.RepositoryRepository
-	gitService *git.Service
-	reposPath  string
+	repoRepo      *repository.RepositoryRepository
+	auditRepo     *repository.AuditRepository
+	importRepo    *repository.ImportRepository
+	gitService    *git.Service
+	quotaService  *quota.Service
+	locks         *jobs.Locks
+	baseURL       string
+	pushListeners []func(repo *models.Repository)
 }
 
-func NewRepositoryHandler(repoRepo *repository.RepositoryRepository, gitService *git.Service, reposPath string) *RepositoryHandler {
+func NewRepositoryHandler(repoRepo *repository.RepositoryRepository, auditRepo *repository.AuditRepository, importRepo *repository.ImportRepository, gitService *git.Service, quotaService *quota.Service, locks *jobs.Locks, baseURL string) *RepositoryHandler {
 	return &RepositoryHandler{
-		repoRepo:   repoRepo,
-		gitService: gitService,
-		reposPath:  reposPath,
+		repoRepo:     repoRepo,
+		auditRepo:    auditRepo,
+		importRepo:   importRepo,
+		gitService:   gitService,
+		quotaService: quotaService,
+		locks:        locks,
+		baseURL:      baseURL,
 	}
 }
 
@@ -42,6 +55,32 @@ type CreateRepositoryRequest struct {
 	// Default branch options
 	CustomBranch  bool   `json:"custom_branch"`
 	DefaultBranch string `json:"default_branch"`
+	// Catalogue templates committed along with the README
+	GitignoreTemplate string `json:"gitignore_template"`
+	License           string `json:"license"`
+}
+
+type GenerateRepositoryRequest struct {
+	Name               string `json:"name" binding:"required"`
+	Description        string `json:"description"`
+	Visibility         string `json:"visibility" binding:"oneof=public private"`
+	IncludeAllBranches bool   `json:"include_all_branches"`
+}
+
+// UpdateRepositoryRequest changes repository settings. Fields left out of
+// the request keep their current value.
+type UpdateRepositoryRequest struct {
+	Description      *string   `json:"description"`
+	Visibility       *string   `json:"visibility" binding:"omitempty,oneof=public private"`
+	DefaultBranch    *string   `json:"default_branch"`
+	Topics           *[]string `json:"topics"`
+	Homepage         *string   `json:"homepage" binding:"omitempty,url"`
+	Archived         *bool     `json:"archived"`
+	IsTemplate       *bool     `json:"is_template"`
+	HasIssues        *bool     `json:"has_issues"`
+	HasWiki          *bool     `json:"has_wiki"`
+	HasMergeRequests *bool     `json:"has_merge_requests"`
+	MergeStrategies  *[]string `json:"merge_strategies" binding:"omitempty,min=1,dive,oneof=merge squash rebase"`
 }
 
 func (h *RepositoryHandler) CreateRepository(c *gin.Context) {
@@ -51,57 +90,205 @@ func (h *RepositoryHandler) CreateRepository(c *gin.Context) {
 		return
 	}
 
-	userID := c.GetUint("user_id")
 	username := c.GetString("username")
+	files, err := initialFiles(req, username, h.baseURL)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Check if repository already exists for this user
-	if _, err := h.repoRepo.FindByUsernameAndName(username, req.Name); err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Repository already exists"})
+	repo := &models.Repository{
+		Name:        req.Name,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		OwnerID:     c.GetUint("user_id"),
+	}
+	if !createRepository(c, h.repoRepo, h.gitService, repo) {
+		return
+	}
+
+	// Set custom default branch if requested
+	branchName := "main"
+	if req.CustomBranch && req.DefaultBranch != "" {
+		branchName = req.DefaultBranch
+		if err := h.setDefaultBranch(username, req.Name, req.DefaultBranch); err != nil {
+			fmt.Printf("Warning: Failed to set custom default branch: %v\n", err)
+			// Don't fail the request if branch setting fails
+		}
+	}
+
+	// All requested files go into a single initial commit
+	if len(files) > 0 {
+		unlock := h.locks.RLock(repo.ID)
+		err := h.seedRepository(username, req.Name, branchName, files)
+		unlock()
+		if err != nil {
+			fmt.Printf("Warning: Failed to create initial commit: %v\n", err)
+			// Don't fail the request if the initial commit fails
+		} else if created, err := h.repoRepo.FindByID(repo.ID); err == nil {
+			// The initial commit counts as a push for background jobs
+			h.notifyPush(created)
+		}
+	}
+
+	c.JSON(http.StatusCreated, repo)
+}
+
+// GenerateRepository creates a repository for the current user from a
+// template repository.
+func (h *RepositoryHandler) GenerateRepository(c *gin.Context) {
+	source, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	if !source.IsTemplate {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Repository is not a template"})
+		return
+	}
+
+	var req GenerateRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if rejectOverQuota(c, h.quotaService) {
 		return
 	}
 
-	// Create repository in database
 	repo := &models.Repository{
 		Name:        req.Name,
 		Description: req.Description,
 		Visibility:  req.Visibility,
-		OwnerID:     userID,
+		OwnerID:     c.GetUint("user_id"),
+	}
+	if !createRepository(c, h.repoRepo, h.gitService, repo) {
+		return
 	}
 
-	if err := h.repoRepo.Create(repo); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create repository"})
+	created, err := h.repoRepo.FindByID(repo.ID)
+	if err == nil {
+		unlock := h.locks.RLock(repo.ID)
+		err = templates.Generate(h.gitService,
+			h.gitService.GetRepositoryPath(source.Owner.Username, source.Name),
+			h.gitService.GetRepositoryPath(created.Owner.Username, created.Name),
+			templates.GenerateOptions{
+				AllBranches: req.IncludeAllBranches,
+				Vars:        templates.Vars{RepoName: created.Name, Owner: created.Owner.Username},
+				Author:      git.Signature{Name: created.Owner.Username, Email: created.Owner.Email},
+			})
+		unlock()
+	}
+	if err != nil {
+		fmt.Printf("Failed to generate repository from template: %v\n", err)
+		// Nothing uses the half-created repository yet, so remove it entirely
+		os.RemoveAll(h.gitService.GetRepositoryPath(c.GetString("username"), repo.Name))
+		h.repoRepo.Purge(repo.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate repository"})
 		return
 	}
 
+	h.notifyPush(created)
+	c.JSON(http.StatusCreated, created)
+}
+
+// rejectOverQuota writes an error and returns true when the current user has
+// reached their storage quota. It guards repositories that are filled as
+// they are created: imports, pull mirrors and generated repositories.
+func rejectOverQuota(c *gin.Context, quotaService *quota.Service) bool {
+	var exceeded *quota.ExceededError
+	err := quotaService.CheckOwner(c.GetUint("user_id"))
+	if errors.As(err, &exceeded) {
+		c.JSON(http.StatusInsufficientStorage, gin.H{"error": fmt.Sprintf("Cannot create repository: %s", exceeded)})
+		return true
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to check storage quota of user %d: %v\n", c.GetUint("user_id"), err)
+	}
+	return false
+}
+
+// createRepository validates the name of a new repository, then creates its
+// database row and bare repository. On failure the error response has
+// already been written.
+func createRepository(c *gin.Context, repoRepo *repository.RepositoryRepository, gitService *git.Service, repo *models.Repository) bool {
+	username := c.GetString("username")
+
+	if err := validateRepositoryName(repo.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	// Check if repository already exists for this user
+	if _, err := repoRepo.FindByUsernameAndName(username, repo.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Repository already exists"})
+		return false
+	}
+
+	// Create repository in database
+	if err := repoRepo.Create(repo); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create repository"})
+		return false
+	}
+
 	// Initialize git repository
-	if err := h.gitService.InitBareRepository(username, req.Name); err != nil {
+	if err := gitService.InitBareRepository(username, repo.Name); err != nil {
 		// Clean up database entry if git init fails
-		h.repoRepo.Delete(repo.ID)
+		repoRepo.Purge(repo.ID)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize git repository"})
-		return
+		return false
 	}
 
-	// Set custom default branch if requested
-	if req.CustomBranch && req.DefaultBranch != "" {
-		if err := h.setDefaultBranch(username, req.Name, req.DefaultBranch); err != nil {
-			fmt.Printf("Warning: Failed to set custom default branch: %v\n", err)
-			// Don't fail the request if branch setting fails
-		}
+	return true
+}
+
+// initialFiles builds the README, .gitignore and LICENSE requested for a
+// new repository.
+func initialFiles(req CreateRepositoryRequest, username, baseURL string) ([]git.FileChange, error) {
+	vars := templates.Vars{
+		RepoName: req.Name,
+		Owner:    username,
+		CloneURL: fmt.Sprintf("%s/git/%s/%s.git", baseURL, username, req.Name),
 	}
+	files := []git.FileChange{}
 
-	// Create README file if requested
 	if req.AddReadme {
-		branchName := "main"
-		if req.CustomBranch && req.DefaultBranch != "" {
-			branchName = req.DefaultBranch
+		filename, content, err := templates.Readme(req.ReadmeType, req.ReadmeTitle, vars)
+		if err != nil {
+			return nil, err
 		}
-		if err := h.createReadmeFileWithBranch(username, req.Name, req.ReadmeType, req.ReadmeTitle, branchName); err != nil {
-			fmt.Printf("Warning: Failed to create README file: %v\n", err)
-			// Don't fail the request if README creation fails
+		files = append(files, git.FileChange{Path: filename, Content: content})
+	}
+
+	if req.GitignoreTemplate != "" {
+		_, content, err := templates.Gitignore(req.GitignoreTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("unknown gitignore template: %s", req.GitignoreTemplate)
 		}
+		files = append(files, git.FileChange{Path: ".gitignore", Content: content})
 	}
 
-	c.JSON(http.StatusCreated, repo)
+	if req.License != "" {
+		_, content, err := templates.LicenseText(req.License, time.Now().Year(), vars)
+		if err != nil {
+			return nil, fmt.Errorf("unknown license: %s", req.License)
+		}
+		files = append(files, git.FileChange{Path: "LICENSE", Content: content})
+	}
+
+	return files, nil
+}
+
+// seedRepository makes the initial commit of a new repository on branch.
+func (h *RepositoryHandler) seedRepository(username, repoName, branch string, files []git.FileChange) error {
+	_, err := h.gitService.CommitFiles(h.gitService.GetRepositoryPath(username, repoName), git.CommitOptions{
+		Branch:  branch,
+		Message: "Initial commit",
+		Author:  git.Signature{Name: "GitLab Tool", Email: "system@gitlab-tool.local"},
+		Files:   files,
+	})
+	return err
 }
 
 func (h *RepositoryHandler) ListRepositories(c *gin.Context) {
@@ -140,6 +327,134 @@ func (h *RepositoryHandler) GetRepository(c *gin.Context) {
 	c.JSON(http.StatusOK, repo)
 }
 
+func (h *RepositoryHandler) UpdateRepository(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !canWriteRepository(repo, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var req UpdateRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.DefaultBranch != nil {
+		if err := h.validateDefaultBranch(repo, *req.DefaultBranch); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	previousVisibility := repo.Visibility
+	if req.Description != nil {
+		repo.Description = *req.Description
+	}
+	if req.Visibility != nil {
+		repo.Visibility = *req.Visibility
+	}
+	if req.Topics != nil {
+		repo.Topics = *req.Topics
+	}
+	if req.Homepage != nil {
+		repo.Homepage = *req.Homepage
+	}
+	if req.Archived != nil {
+		repo.Archived = *req.Archived
+	}
+	if req.IsTemplate != nil {
+		repo.IsTemplate = *req.IsTemplate
+	}
+	if req.HasIssues != nil {
+		repo.HasIssues = *req.HasIssues
+	}
+	if req.HasWiki != nil {
+		repo.HasWiki = *req.HasWiki
+	}
+	if req.HasMergeRequests != nil {
+		repo.HasMergeRequests = *req.HasMergeRequests
+	}
+	if req.MergeStrategies != nil {
+		repo.MergeStrategies = *req.MergeStrategies
+	}
+
+	// The branch is switched first so a failure leaves the settings
+	// untouched; a failed save switches it back
+	repoPath := h.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	previousBranch, _ := h.gitService.DefaultBranch(repoPath)
+	if req.DefaultBranch != nil {
+		if err := h.setDefaultBranch(repo.Owner.Username, repo.Name, *req.DefaultBranch); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set default branch"})
+			return
+		}
+	}
+
+	if err := h.repoRepo.Update(repo); err != nil {
+		if req.DefaultBranch != nil && previousBranch != "" {
+			if err := h.setDefaultBranch(repo.Owner.Username, repo.Name, previousBranch); err != nil {
+				fmt.Printf("Warning: Failed to restore default branch of repository %d: %v\n", repo.ID, err)
+			}
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update repository"})
+		return
+	}
+
+	if repo.Visibility != previousVisibility {
+		event := &models.AuditEvent{
+			ActorID:    userID,
+			Action:     "repository.visibility_changed",
+			TargetType: "repository",
+			TargetID:   repo.ID,
+			Details:    fmt.Sprintf("%s -> %s", previousVisibility, repo.Visibility),
+		}
+		if err := h.auditRepo.Create(event); err != nil {
+			fmt.Printf("Warning: Failed to record audit event: %v\n", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, repo)
+}
+
+// validateRepositoryName rejects names that cannot be used as a directory
+// under the owner's namespace.
+func validateRepositoryName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return fmt.Errorf("invalid repository name %q", name)
+	}
+	// "<name>.wiki" is reserved for the wiki of <name>
+	if strings.HasSuffix(name, ".wiki") {
+		return errors.New("Repository name cannot end with .wiki")
+	}
+	return nil
+}
+
+// validateDefaultBranch checks that branch is a valid branch name and, unless
+// the repository is still empty, that it already exists.
+func (h *RepositoryHandler) validateDefaultBranch(repo *models.Repository, branch string) error {
+	if err := exec.Command("git", "check-ref-format", "--branch", branch).Run(); err != nil || strings.HasPrefix(branch, "-") {
+		return fmt.Errorf("invalid branch name: %q", branch)
+	}
+
+	repoPath := h.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	branches, err := h.gitService.ListRefs(repoPath, "refs/heads/")
+	if err != nil || len(branches) == 0 {
+		// Nothing has been pushed yet, so any name can become the default
+		return nil
+	}
+	for _, ref := range branches {
+		if ref == branch {
+			return nil
+		}
+	}
+	return fmt.Errorf("branch %q does not exist", branch)
+}
+
 func (h *RepositoryHandler) DeleteRepository(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -161,21 +476,61 @@ func (h *RepositoryHandler) DeleteRepository(c *gin.Context) {
 		return
 	}
 
+	// Reload under the lock, as a rename or transfer may have moved the
+	// repository while this request waited for pushes to finish
+	unlock := h.locks.Lock(repo.ID)
+	defer unlock()
+	repo, err = h.repoRepo.FindByID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return
+	}
+
+	// Move the git repository files to the trash, from where they can be
+	// restored until the purger removes them
+	if err := h.gitService.TrashRepository(repo.ID, repo.Owner.Username, repo.Name); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move repository to trash"})
+		return
+	}
+
 	// Delete from database
 	if err := h.repoRepo.Delete(uint(id)); err != nil {
+		if err := h.gitService.RestoreRepository(repo.ID, repo.Owner.Username, repo.Name); err != nil {
+			fmt.Printf("Warning: Failed to move repository back from trash: %v\n", err)
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete repository"})
 		return
 	}
 
-	// Delete git repository files
-	username := c.GetString("username")
-	repoPath := h.gitService.GetRepositoryPath(username, repo.Name)
-	if err := exec.Command("rm", "-rf", repoPath).Run(); err != nil {
-		// Log error but don't fail the request
-		fmt.Printf("Warning: Failed to delete git repository files: %v\n", err)
+	c.JSON(http.StatusOK, gin.H{"message": "Repository deleted successfully"})
+}
+
+func (h *RepositoryHandler) StarRepository(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Repository deleted successfully"})
+	if err := h.repoRepo.AddStar(c.GetUint("user_id"), repo.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to star repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository starred"})
+}
+
+func (h *RepositoryHandler) UnstarRepository(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	if err := h.repoRepo.RemoveStar(c.GetUint("user_id"), repo.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unstar repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository unstarred"})
 }
 
 func (h *RepositoryHandler) CloneRepository(c *gin.Context) {
@@ -211,323 +566,143 @@ func (h *RepositoryHandler) GitHTTPBackend(c *gin.Context) {
 		repoName = strings.TrimSuffix(repoName, ".git")
 	}
 
+	// A "<repo>.wiki" path serves the wiki of <repo> with the same permissions
+	isWiki := strings.HasSuffix(repoName, ".wiki")
+	baseName := strings.TrimSuffix(repoName, ".wiki")
+
 	// Check if repository exists in database first
-	_, err := h.repoRepo.FindByUsernameAndName(username, repoName)
+	repo, err := h.repoRepo.FindByUsernameAndName(username, baseName)
 	if err != nil {
-		fmt.Printf("Repository not found in database: %v\n", err)
-		// For Git protocol, return minimal error without HTTP headers
-		c.Data(http.StatusNotFound, "text/plain", []byte("Repository not found"))
-		return
-	}
-
-	// Check if repository exists on filesystem
-	if !h.gitService.RepositoryExists(username, repoName) {
-		fmt.Printf("Repository not found on filesystem, initializing...\n")
-		// Repository doesn't exist on filesystem, try to initialize it
-		if err := h.gitService.InitBareRepository(username, repoName); err != nil {
-			fmt.Printf("Failed to initialize repository: %v\n", err)
+		// The repository may have been renamed or transferred
+		repo, err = h.repoRepo.FindByRedirect(username, baseName)
+		if err != nil {
+			fmt.Printf("Repository not found in database: %v\n", err)
 			// For Git protocol, return minimal error without HTTP headers
-			c.Data(http.StatusInternalServerError, "text/plain", []byte("Failed to initialize repository"))
+			c.Data(http.StatusNotFound, "text/plain", []byte("Repository not found"))
 			return
 		}
-		fmt.Printf("Repository initialized successfully\n")
-	}
 
-	// Ensure repository has a proper HEAD reference
-	repoPath := h.gitService.GetRepositoryPath(username, repoName)
-	headPath := filepath.Join(repoPath, "HEAD")
-	if _, err := os.Stat(headPath); err != nil {
-		fmt.Printf("HEAD file not found, creating default branch...\n")
-		// Create a default branch to fix the HEAD reference
-		if err := h.ensureDefaultBranch(repoPath); err != nil {
-			fmt.Printf("Warning: Failed to create default branch: %v\n", err)
+		suffix := ".git"
+		if isWiki {
+			suffix = ".wiki.git"
+		}
+		if c.Request.Method == http.MethodGet {
+			// git follows the redirect of the initial ref advertisement and
+			// uses the new URL for the rest of the operation
+			location := fmt.Sprintf("/git/%s/%s%s/%s", repo.Owner.Username, repo.Name, suffix, action)
+			if c.Request.URL.RawQuery != "" {
+				location += "?" + c.Request.URL.RawQuery
+			}
+			c.Redirect(http.StatusMovedPermanently, location)
+			return
 		}
-	}
 
-	// Set environment variables for git-http-backend
-	// CRITICAL: git-http-backend expects CGI-style environment variables
-	env := []string{
-		fmt.Sprintf("GIT_PROJECT_ROOT=%s", h.reposPath), // Points to /tmp/repos
-		fmt.Sprintf("GIT_HTTP_EXPORT_ALL=1"),
-		fmt.Sprintf("REMOTE_USER=%s", username),
-		fmt.Sprintf("PATH=%s", os.Getenv("PATH")),
-		fmt.Sprintf("REQUEST_METHOD=%s", c.Request.Method),
-		fmt.Sprintf("QUERY_STRING=%s", c.Request.URL.RawQuery),
-		fmt.Sprintf("CONTENT_TYPE=%s", c.GetHeader("Content-Type")),
-		fmt.Sprintf("CONTENT_LENGTH=%s", c.GetHeader("Content-Length")),
-		fmt.Sprintf("HTTP_ACCEPT=%s", c.GetHeader("Accept")),
-		fmt.Sprintf("HTTP_USER_AGENT=%s", c.GetHeader("User-Agent")),
-		// PATH_INFO should be the repository path relative to GIT_PROJECT_ROOT
-		fmt.Sprintf("PATH_INFO=/%s/%s.git", username, repoName),
-		fmt.Sprintf("SCRIPT_NAME=/git"),
-		// Add server info
-		fmt.Sprintf("SERVER_NAME=localhost"),
-		fmt.Sprintf("SERVER_PORT=8080"),
-		// Add request info
-		fmt.Sprintf("REQUEST_URI=%s", c.Request.URL.RequestURI()),
-	}
-
-	// Execute git-http-backend (NO EXTRA ARGUMENTS!)
-	cmd := exec.Command("git", "http-backend")
-	cmd.Env = env
-	cmd.Dir = h.reposPath
-	cmd.Stdin = c.Request.Body
-	cmd.Stdout = c.Writer
-
-	// Capture stderr for debugging
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	fmt.Printf("Executing git http-backend\n")
-	fmt.Printf("Working directory: %s\n", h.reposPath)
-	fmt.Printf("Environment: %v\n", env)
-
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Git operation failed: %v\n", err)
-		fmt.Printf("Git stderr output: %s\n", stderr.String())
-		c.Data(http.StatusInternalServerError, "text/plain", []byte(fmt.Sprintf("Git operation failed: %v", err)))
-		return
-	}
-
-	fmt.Printf("Git operation completed successfully\n")
-	if stderr.Len() > 0 {
-		fmt.Printf("Git stderr output: %s\n", stderr.String())
+		// Clients that do not follow redirects are served from the new location
+		username = repo.Owner.Username
+		baseName = repo.Name
+		repoName = strings.TrimSuffix(repo.Name+suffix, ".git")
 	}
-}
 
-// createReadmeFile creates a README file and makes an initial commit
-func (h *RepositoryHandler) createReadmeFile(username, repoName, readmeType, readmeTitle string) error {
-	return h.createReadmeFileWithBranch(username, repoName, readmeType, readmeTitle, "main")
-}
-
-// createReadmeFileWithBranch creates a README file and makes an initial commit with a specific branch
-func (h *RepositoryHandler) createReadmeFileWithBranch(username, repoName, readmeType, readmeTitle, branchName string) error {
-	repoPath := h.gitService.GetRepositoryPath(username, repoName)
-
-	// Set default values if not provided
-	if readmeType == "" {
-		readmeType = "markdown"
-	}
-	if readmeTitle == "" {
-		readmeTitle = repoName
+	isPush := githttp.Service(action, c.Query("service")) == githttp.ReceivePack
+	if status := gitAccess(c, repo, isPush); status != http.StatusOK {
+		c.Data(status, "text/plain; charset=utf-8", []byte(http.StatusText(status)+"\n"))
+		return
 	}
 
-	// Create README content based on type
-	var readmeContent string
-	var filename string
-
-	switch readmeType {
-	case "markdown":
-		filename = "README.md"
-		readmeContent = fmt.Sprintf(`# %s
-
-%s
-
-## Description
-
-This repository was created using GitLab-like Tool.
-
-## Getting Started
-
-`+"```"+`bash
-# Clone the repository
-git clone http://localhost:8080/git/%s/%s.git
-
-# Navigate to the project
-cd %s
-
-# Start developing!
-`+"```"+`
-
-## Contributing
-
-1. Fork the repository
-2. Create your feature branch (`+"`"+`git checkout -b feature/amazing-feature`+"`"+`)
-3. Commit your changes (`+"`"+`git commit -m 'Add some amazing feature'`+"`"+`)
-4. Push to the branch (`+"`"+`git push origin feature/amazing-feature`+"`"+`)
-5. Open a Pull Request
-
-## License
-
-This project is open source and available under the MIT License.
-`, readmeTitle, repoName, username, repoName, repoName, repoName)
-
-	case "text":
-		filename = "README.txt"
-		readmeContent = fmt.Sprintf(`%s
-
-%s
-
-Description:
-This repository was created using GitLab-like Tool.
-
-Getting Started:
-1. Clone the repository: git clone http://localhost:8080/git/%s/%s.git
-2. Navigate to the project: cd %s
-3. Start developing!
-
-Contributing:
-1. Fork the repository
-2. Create your feature branch
-3. Commit your changes
-4. Push to the branch
-5. Open a Pull Request
-
-License:
-This project is open source and available under the MIT License.
-`, readmeTitle, repoName, username, repoName, repoName)
-
-	default:
-		return fmt.Errorf("unsupported readme type: %s", readmeType)
+	// Archived repositories and their wikis can still be cloned but not pushed to
+	if isPush && repo.Archived {
+		c.Data(http.StatusForbidden, "text/plain; charset=utf-8",
+			[]byte(fmt.Sprintf("Repository %s/%s is archived and read-only; unarchive it to push\n", repo.Owner.Username, repo.Name)))
+		return
 	}
 
-	// Create the README file
-	readmePath := filepath.Join(repoPath, filename)
-	if err := os.WriteFile(readmePath, []byte(readmeContent), 0644); err != nil {
-		return fmt.Errorf("failed to create README file: %w", err)
+	// Pull mirrors only change by fetching from their upstream
+	if isPush && !isWiki && repo.IsMirror {
+		c.Data(http.StatusForbidden, "text/plain; charset=utf-8",
+			[]byte(fmt.Sprintf("Repository %s/%s is a pull mirror and cannot be pushed to\n", repo.Owner.Username, repo.Name)))
+		return
 	}
 
-	// Initialize git repository and make initial commit
-	if err := h.initializeGitRepositoryWithBranch(repoPath, filename, branchName); err != nil {
-		return fmt.Errorf("failed to initialize git repository: %w", err)
+	// A push would race with the fetch filling the repository
+	if isPush && !isWiki && h.importRepo.IsImporting(repo.ID) {
+		c.Data(http.StatusConflict, "text/plain; charset=utf-8",
+			[]byte(fmt.Sprintf("Repository %s/%s is being imported; push once the import has finished\n", repo.Owner.Username, repo.Name)))
+		return
 	}
 
-	fmt.Printf("README file created successfully: %s\n", filename)
-	return nil
-}
-
-// initializeGitRepository initializes the git repository and makes the initial commit
-func (h *RepositoryHandler) initializeGitRepository(repoPath, filename string) error {
-	return h.initializeGitRepositoryWithBranch(repoPath, filename, "main")
-}
-
-// initializeGitRepositoryWithBranch initializes the git repository with a specific default branch
-func (h *RepositoryHandler) initializeGitRepositoryWithBranch(repoPath, filename, branchName string) error {
-	// For bare repositories, we need to use a different approach
-	// Create a temporary directory, clone the bare repo, add README, and push back
-
-	tempDir, err := os.MkdirTemp("", "git-temp-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temp directory: %w", err)
-	}
-	defer os.RemoveAll(tempDir)
-
-	// Clone the bare repository to the temp directory
-	cloneCmd := exec.Command("git", "clone", repoPath, tempDir)
-	if err := cloneCmd.Run(); err != nil {
-		// If clone fails, the repository might be empty, so we initialize it
-		initCmd := exec.Command("git", "init", "-b", branchName)
-		initCmd.Dir = tempDir
-		if err := initCmd.Run(); err != nil {
-			return fmt.Errorf("failed to initialize temp repository: %w", err)
-		}
+	if isWiki && !repo.HasWiki {
+		c.Data(http.StatusNotFound, "text/plain", []byte("Wiki is disabled"))
+		return
+	}
 
-		// Add the bare repository as origin
-		remoteCmd := exec.Command("git", "remote", "add", "origin", repoPath)
-		remoteCmd.Dir = tempDir
-		if err := remoteCmd.Run(); err != nil {
-			return fmt.Errorf("failed to add remote origin: %w", err)
+	if isPush {
+		var exceeded *quota.ExceededError
+		if err := h.quotaService.Check(repo, 0); errors.As(err, &exceeded) {
+			c.Data(http.StatusForbidden, "text/plain; charset=utf-8",
+				[]byte(fmt.Sprintf("Repository %s/%s cannot be pushed to: %s; delete data or ask an administrator to raise the limit\n", repo.Owner.Username, repo.Name, exceeded)))
+			return
+		} else if err != nil {
+			fmt.Printf("Warning: Failed to check storage quota of repository %d: %v\n", repo.ID, err)
 		}
 	}
 
-	// Change to temp directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
+	if isWiki && !h.gitService.WikiExists(username, baseName) {
+		// Wikis are created lazily, so the first push may arrive before any page exists
+		if err := h.gitService.InitBareRepositoryAt(h.gitService.GetWikiPath(username, baseName), "main"); err != nil {
+			fmt.Printf("Failed to initialize wiki: %v\n", err)
+			c.Data(http.StatusInternalServerError, "text/plain", []byte("Failed to initialize wiki"))
+			return
+		}
 	}
-	defer os.Chdir(originalDir)
 
-	if err := os.Chdir(tempDir); err != nil {
-		return fmt.Errorf("failed to change to temp directory: %w", err)
+	// A repository whose data has gone missing must not be replaced by an
+	// empty one that clients would happily push to; the integrity check
+	// reports it to administrators instead
+	if !isWiki && !h.gitService.RepositoryExists(username, repoName) {
+		fmt.Printf("Warning: Repository %d has no data on disk at %s\n", repo.ID, h.gitService.GetRepositoryPath(username, repoName))
+		c.Data(http.StatusServiceUnavailable, "text/plain; charset=utf-8",
+			[]byte(fmt.Sprintf("Repository %s/%s is unavailable; ask an administrator to check its storage\n", repo.Owner.Username, repo.Name)))
+		return
 	}
 
-	// Create the README file in the temp directory
-	readmePath := filepath.Join(tempDir, filename)
-	readmeContent, err := os.ReadFile(filepath.Join(repoPath, filename))
-	if err != nil {
-		return fmt.Errorf("failed to read README content from bare repo: %w", err)
-	}
+	repoPath := h.gitService.GetRepositoryPath(username, repoName)
 
-	if err := os.WriteFile(readmePath, readmeContent, 0644); err != nil {
-		return fmt.Errorf("failed to write README file in temp dir: %w", err)
+	// Maintenance repacks objects that a push may be relying on
+	if action == "git-receive-pack" {
+		unlock := h.locks.RLock(repo.ID)
+		defer unlock()
 	}
 
-	// Add the README file
-	addCmd := exec.Command("git", "add", filename)
-	addCmd.Dir = tempDir
-	if err := addCmd.Run(); err != nil {
-		return fmt.Errorf("failed to add README file: %w", err)
+	ok := serveGitHTTPBackend(c, repoPath, action, c.GetString("username"))
+	if ok && action == "git-receive-pack" {
+		if isWiki {
+			// Wiki pushes only change the size of the repository
+			go h.quotaService.Schedule(repo)
+		} else {
+			h.notifyPush(repo)
+		}
 	}
+}
 
-	// Make initial commit
-	commitCmd := exec.Command("git", "commit", "-m", "Initial commit: Add README file")
-	commitCmd.Dir = tempDir
-	commitCmd.Env = append(os.Environ(), "GIT_AUTHOR_NAME=GitLab Tool", "GIT_AUTHOR_EMAIL=system@gitlab-tool.local", "GIT_COMMITTER_NAME=GitLab Tool", "GIT_COMMITTER_EMAIL=system@gitlab-tool.local")
-	if err := commitCmd.Run(); err != nil {
-		return fmt.Errorf("failed to make initial commit: %w", err)
-	}
+// OnPush registers a listener that runs in the background after every
+// successful push to a repository.
+func (h *RepositoryHandler) OnPush(listener func(repo *models.Repository)) {
+	h.pushListeners = append(h.pushListeners, listener)
+}
 
-	// Push to the bare repository
-	pushCmd := exec.Command("git", "push", "origin", branchName)
-	pushCmd.Dir = tempDir
-	if err := pushCmd.Run(); err != nil {
-		return fmt.Errorf("failed to push to bare repository: %w", err)
+func (h *RepositoryHandler) notifyPush(repo *models.Repository) {
+	for _, listener := range h.pushListeners {
+		go listener(repo)
 	}
+}
 
-	return nil
+// serveGitHTTPBackend answers a smart HTTP request for the bare repository
+// at repoPath and reports whether it succeeded.
+func serveGitHTTPBackend(c *gin.Context, repoPath, action, remoteUser string) bool {
+	return githttp.Serve(c.Writer, c.Request, repoPath, action, remoteUser)
 }
 
 // setDefaultBranch sets the default branch for a bare repository
 func (h *RepositoryHandler) setDefaultBranch(username, repoName, branchName string) error {
-	repoPath := h.gitService.GetRepositoryPath(username, repoName)
-
-	// For bare repositories, we need to create the branch and set it as default
-	// This is done by creating a symbolic ref HEAD pointing to the branch
-	cmd := exec.Command("git", "symbolic-ref", "HEAD", fmt.Sprintf("refs/heads/%s", branchName))
-	cmd.Dir = repoPath
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to set default branch: %w", err)
-	}
-
-	fmt.Printf("Default branch set to '%s' for repository %s/%s\n", branchName, username, repoName)
-	return nil
-}
-
-// ensureDefaultBranch ensures that a default branch (e.g., 'main') exists and is the HEAD.
-// This is necessary because git-http-backend expects a HEAD file pointing to a branch.
-func (h *RepositoryHandler) ensureDefaultBranch(repoPath string) error {
-	// Change to repository directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return fmt.Errorf("failed to get current directory: %w", err)
-	}
-	defer os.Chdir(originalDir)
-
-	if err := os.Chdir(repoPath); err != nil {
-		return fmt.Errorf("failed to change to repository directory: %w", err)
-	}
-
-	// Check if 'main' branch exists
-	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", "refs/heads/main")
-	cmd.Dir = repoPath
-	if err := cmd.Run(); err != nil {
-		fmt.Printf("Main branch not found, creating...\n")
-		// Create the 'main' branch
-		cmd := exec.Command("git", "branch", "main")
-		cmd.Dir = repoPath
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to create main branch: %w", err)
-		}
-		fmt.Printf("Main branch created successfully\n")
-	}
-
-	// Set HEAD to point to the 'main' branch
-	cmd = exec.Command("git", "symbolic-ref", "HEAD", "refs/heads/main")
-	cmd.Dir = repoPath
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to set HEAD to main branch: %w", err)
-	}
-	fmt.Printf("HEAD set to main branch successfully\n")
-
-	return nil
+	return h.gitService.SetDefaultBranch(h.gitService.GetRepositoryPath(username, repoName), branchName)
 }
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"gitlab-tool/internal/repository"
+	"gitlab-tool/internal/search"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchHandler struct {
+	repoRepo      *repository.RepositoryRepository
+	userRepo      *repository.UserRepository
+	searchService *search.Service
+}
+
+func NewSearchHandler(repoRepo *repository.RepositoryRepository, userRepo *repository.UserRepository, searchService *search.Service) *SearchHandler {
+	return &SearchHandler{
+		repoRepo:      repoRepo,
+		userRepo:      userRepo,
+		searchService: searchService,
+	}
+}
+
+// UserSummary is the public view of a user; emails are never exposed in search.
+type UserSummary struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SearchCode searches the default branch of every repository the user can
+// read. The query supports repo:, path: and lang: filters; ?regex=true treats
+// the pattern as a regular expression.
+func (h *SearchHandler) SearchCode(c *gin.Context) {
+	query, err := search.ParseQuery(c.Query("q"), c.Query("regex") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	repos, err := h.repoRepo.FindReadableBy(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repositories"})
+		return
+	}
+
+	results := h.searchService.Search(query, repos)
+	c.JSON(http.StatusOK, gin.H{
+		"total_count": len(results),
+		"results":     results,
+	})
+}
+
+// ExploreRepositories lists public repositories.
+func (h *SearchHandler) ExploreRepositories(c *gin.Context) {
+	h.listRepositories(c, repository.RepositorySearch{})
+}
+
+// SearchRepositories matches repository name, description, topics and owner
+// among public repositories and the caller's own.
+func (h *SearchHandler) SearchRepositories(c *gin.Context) {
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	h.listRepositories(c, repository.RepositorySearch{
+		Query:    q,
+		ViewerID: c.GetUint("user_id"),
+	})
+}
+
+func (h *SearchHandler) listRepositories(c *gin.Context, params repository.RepositorySearch) {
+	params.Sort = c.DefaultQuery("sort", "updated")
+	if params.Sort != "updated" && params.Sort != "stars" && params.Sort != "created" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of updated, stars, created"})
+		return
+	}
+
+	pagination := parsePagination(c)
+	params.Offset = pagination.Offset()
+	params.Limit = pagination.PerPage
+
+	repos, total, err := h.repoRepo.Search(params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search repositories"})
+		return
+	}
+
+	writePage(c, pagination, total, repos)
+}
+
+func (h *SearchHandler) SearchUsers(c *gin.Context) {
+	q := c.Query("q")
+	if q == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
+	}
+
+	pagination := parsePagination(c)
+	users, total, err := h.userRepo.Search(q, pagination.Offset(), pagination.PerPage)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search users"})
+		return
+	}
+
+	summaries := make([]UserSummary, 0, len(users))
+	for _, user := range users {
+		summaries = append(summaries, UserSummary{ID: user.ID, Username: user.Username, CreatedAt: user.CreatedAt})
+	}
+
+	writePage(c, pagination, total, summaries)
+}
@@ -0,0 +1,385 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/githttp"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+const snippetBranch = "main"
+
+type SnippetHandler struct {
+	snippetRepo *repository.SnippetRepository
+	userRepo    *repository.UserRepository
+	gitService  *git.Service
+}
+
+func NewSnippetHandler(snippetRepo *repository.SnippetRepository, userRepo *repository.UserRepository, gitService *git.Service) *SnippetHandler {
+	return &SnippetHandler{
+		snippetRepo: snippetRepo,
+		userRepo:    userRepo,
+		gitService:  gitService,
+	}
+}
+
+type SnippetFileRequest struct {
+	Name    string `json:"name" binding:"required"`
+	Content string `json:"content"`
+	Delete  bool   `json:"delete"`
+}
+
+type CreateSnippetRequest struct {
+	Title       string               `json:"title" binding:"required"`
+	Description string               `json:"description"`
+	Visibility  string               `json:"visibility" binding:"omitempty,oneof=public private"`
+	Files       []SnippetFileRequest `json:"files" binding:"required,min=1,dive"`
+}
+
+type UpdateSnippetRequest struct {
+	Title       *string              `json:"title"`
+	Description *string              `json:"description"`
+	Visibility  *string              `json:"visibility" binding:"omitempty,oneof=public private"`
+	Files       []SnippetFileRequest `json:"files" binding:"dive"`
+	Message     string               `json:"message"`
+}
+
+type SnippetFile struct {
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Content string `json:"content"`
+}
+
+type SnippetResponse struct {
+	models.Snippet
+	Files []SnippetFile `json:"files"`
+}
+
+func (h *SnippetHandler) CreateSnippet(c *gin.Context) {
+	var req CreateSnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, err := snippetFileChanges(req.Files)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	author, ok := currentSignature(c, h.userRepo)
+	if !ok {
+		return
+	}
+
+	if req.Visibility == "" {
+		req.Visibility = "private"
+	}
+
+	snippet := &models.Snippet{
+		Title:       req.Title,
+		Description: req.Description,
+		Visibility:  req.Visibility,
+		OwnerID:     c.GetUint("user_id"),
+	}
+
+	if err := h.snippetRepo.Create(snippet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create snippet"})
+		return
+	}
+
+	snippetPath := h.gitService.GetSnippetPath(snippet.ID)
+	if err := h.gitService.InitBareRepositoryAt(snippetPath, snippetBranch); err != nil {
+		h.snippetRepo.Delete(snippet.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize snippet repository"})
+		return
+	}
+
+	_, err = h.gitService.CommitFiles(snippetPath, git.CommitOptions{
+		Branch:  snippetBranch,
+		Message: "Create snippet",
+		Author:  author,
+		Files:   changes,
+	})
+	if err != nil {
+		// Clean up so a half-created snippet never shows up
+		os.RemoveAll(snippetPath)
+		h.snippetRepo.Delete(snippet.ID)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit snippet files"})
+		return
+	}
+
+	h.respondWithSnippet(c, http.StatusCreated, snippet)
+}
+
+func (h *SnippetHandler) ListSnippets(c *gin.Context) {
+	snippets, err := h.snippetRepo.FindByOwnerID(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch snippets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, snippets)
+}
+
+func (h *SnippetHandler) ListPublicSnippets(c *gin.Context) {
+	snippets, err := h.snippetRepo.ListPublic()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch snippets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, snippets)
+}
+
+func (h *SnippetHandler) GetSnippet(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok {
+		return
+	}
+
+	h.respondWithSnippet(c, http.StatusOK, snippet)
+}
+
+func (h *SnippetHandler) UpdateSnippet(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok {
+		return
+	}
+
+	if snippet.OwnerID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var req UpdateSnippetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if len(req.Files) > 0 {
+		changes, err := snippetFileChanges(req.Files)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		author, ok := currentSignature(c, h.userRepo)
+		if !ok {
+			return
+		}
+
+		if req.Message == "" {
+			req.Message = "Update snippet"
+		}
+
+		_, err = h.gitService.CommitFiles(h.gitService.GetSnippetPath(snippet.ID), git.CommitOptions{
+			Branch:  snippetBranch,
+			Message: req.Message,
+			Author:  author,
+			Files:   changes,
+		})
+		if err != nil && !errors.Is(err, git.ErrNoChanges) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit snippet files"})
+			return
+		}
+	}
+
+	if req.Title != nil {
+		snippet.Title = *req.Title
+	}
+	if req.Description != nil {
+		snippet.Description = *req.Description
+	}
+	if req.Visibility != nil {
+		snippet.Visibility = *req.Visibility
+	}
+
+	if err := h.snippetRepo.Update(snippet); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update snippet"})
+		return
+	}
+
+	h.respondWithSnippet(c, http.StatusOK, snippet)
+}
+
+func (h *SnippetHandler) DeleteSnippet(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok {
+		return
+	}
+
+	if snippet.OwnerID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if err := h.snippetRepo.Delete(snippet.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete snippet"})
+		return
+	}
+
+	if err := os.RemoveAll(h.gitService.GetSnippetPath(snippet.ID)); err != nil {
+		// Log error but don't fail the request
+		fmt.Printf("Warning: Failed to delete snippet repository files: %v\n", err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Snippet deleted successfully"})
+}
+
+func (h *SnippetHandler) ListRevisions(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok {
+		return
+	}
+
+	commits, err := h.gitService.Log(h.gitService.GetSnippetPath(snippet.ID), snippetBranch, 0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch snippet revisions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, commits)
+}
+
+// RawFile serves a single snippet file, optionally at an older revision via ?ref=.
+func (h *SnippetHandler) RawFile(c *gin.Context) {
+	snippet, ok := h.loadSnippet(c)
+	if !ok {
+		return
+	}
+
+	ref := c.DefaultQuery("ref", snippetBranch)
+	name := c.Param("filename")
+	if err := validateSnippetFileName(name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, err := h.gitService.ReadFile(h.gitService.GetSnippetPath(snippet.ID), ref, name)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Data(http.StatusOK, "text/plain; charset=utf-8", content)
+}
+
+// GitHTTPBackend serves /git/snippets/<id>.git so snippets can be cloned.
+func (h *SnippetHandler) GitHTTPBackend(c *gin.Context) {
+	id, err := strconv.ParseUint(strings.TrimSuffix(c.Param("id"), ".git"), 10, 32)
+	if err != nil {
+		c.Data(http.StatusNotFound, "text/plain", []byte("Snippet not found"))
+		return
+	}
+
+	snippet, err := h.snippetRepo.FindByID(uint(id))
+	if err != nil {
+		c.Data(http.StatusNotFound, "text/plain", []byte("Snippet not found"))
+		return
+	}
+
+	h.serveSnippetGit(c, snippet)
+}
+
+// serveSnippetGit serves a git request for a snippet the client may read,
+// with the same authentication rules as repositories.
+func (h *SnippetHandler) serveSnippetGit(c *gin.Context, snippet *models.Snippet) {
+	if status := gitAccessStatus(c, canReadSnippet(snippet, c.GetUint("user_id"))); status != http.StatusOK {
+		c.Data(status, "text/plain; charset=utf-8", []byte(http.StatusText(status)+"\n"))
+		return
+	}
+
+	// Snippets are edited through the API, never pushed to
+	action := strings.TrimPrefix(c.Param("action"), "/")
+	if githttp.Service(action, c.Query("service")) == githttp.ReceivePack {
+		c.Data(http.StatusForbidden, "text/plain; charset=utf-8", []byte("Snippets cannot be pushed to; edit them through the API\n"))
+		return
+	}
+
+	serveGitHTTPBackend(c, h.gitService.GetSnippetPath(snippet.ID), action, "")
+}
+
+// canReadSnippet reports whether the user may view a snippet.
+func canReadSnippet(snippet *models.Snippet, userID uint) bool {
+	return snippet.OwnerID == userID || snippet.Visibility != "private"
+}
+
+func (h *SnippetHandler) loadSnippet(c *gin.Context) (*models.Snippet, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid snippet ID"})
+		return nil, false
+	}
+
+	snippet, err := h.snippetRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Snippet not found"})
+		return nil, false
+	}
+
+	if !canReadSnippet(snippet, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	return snippet, true
+}
+
+func (h *SnippetHandler) respondWithSnippet(c *gin.Context, status int, snippet *models.Snippet) {
+	snippetPath := h.gitService.GetSnippetPath(snippet.ID)
+	entries, err := h.gitService.ListTree(snippetPath, snippetBranch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read snippet files"})
+		return
+	}
+
+	files := []SnippetFile{}
+	for _, entry := range entries {
+		content, err := h.gitService.ReadFile(snippetPath, snippetBranch, entry.Path)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read snippet files"})
+			return
+		}
+		files = append(files, SnippetFile{Name: entry.Path, Size: entry.Size, Content: string(content)})
+	}
+
+	c.JSON(status, SnippetResponse{Snippet: *snippet, Files: files})
+}
+
+func snippetFileChanges(files []SnippetFileRequest) ([]git.FileChange, error) {
+	changes := make([]git.FileChange, 0, len(files))
+	for _, file := range files {
+		if err := validateSnippetFileName(file.Name); err != nil {
+			return nil, err
+		}
+		changes = append(changes, git.FileChange{
+			Path:    file.Name,
+			Content: []byte(file.Content),
+			Delete:  file.Delete,
+		})
+	}
+	return changes, nil
+}
+
+// validateSnippetFileName only allows flat file names so snippets stay a
+// simple collection of files.
+func validateSnippetFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") || path.Clean(name) != name {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	return nil
+}
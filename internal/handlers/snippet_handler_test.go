@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TestSnippetGitAccess clones a private snippet over HTTP as its owner,
+// another user and an anonymous client.
+func TestSnippetGitAccess(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	gitService := git.NewService(t.TempDir())
+	snippet := &models.Snippet{ID: 1, OwnerID: 1, Visibility: "private"}
+	snippetPath := gitService.GetSnippetPath(snippet.ID)
+	if err := gitService.InitBareRepositoryAt(snippetPath, snippetBranch); err != nil {
+		t.Fatalf("InitBareRepositoryAt failed: %v", err)
+	}
+	if _, err := gitService.CommitFiles(snippetPath, git.CommitOptions{
+		Branch: snippetBranch, Message: "Create snippet", Author: git.Signature{Name: "alice", Email: "alice@example.com"},
+		Files: []git.FileChange{{Path: "hello.sh", Content: []byte("echo hello\n")}},
+	}); err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+
+	// Stands in for GitAuthMiddleware, which needs the database
+	users := map[string]uint{"alice": 1, "bob": 2}
+	h := &SnippetHandler{gitService: gitService}
+	router := gin.New()
+	router.Any("/git/snippets/:id/*action", func(c *gin.Context) {
+		if username, _, ok := c.Request.BasicAuth(); ok {
+			c.Set("user_id", users[username])
+		}
+		h.serveSnippetGit(c, snippet)
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	clone := func(userinfo string) (string, error) {
+		url := strings.Replace(server.URL, "://", "://"+userinfo, 1) + "/git/snippets/1.git"
+		cmd := exec.Command("git", "clone", "--quiet", url, filepath.Join(t.TempDir(), "clone"))
+		cmd.Env = append(os.Environ(), "GIT_CONFIG_NOSYSTEM=1", "HOME="+t.TempDir(), "GIT_TERMINAL_PROMPT=0")
+		output, err := cmd.CombinedOutput()
+		return string(output), err
+	}
+
+	if output, err := clone(""); err == nil {
+		t.Error("anonymous clone of a private snippet succeeded")
+	} else if !strings.Contains(output, "could not read Username") {
+		t.Errorf("anonymous clone was not asked for credentials: %s", output)
+	}
+	if _, err := clone("bob:secret@"); err == nil {
+		t.Error("clone of another user's private snippet succeeded")
+	}
+	if output, err := clone("alice:secret@"); err != nil {
+		t.Errorf("owner clone failed: %v: %s", err, output)
+	}
+
+	resp, err := http.Get(server.URL + "/git/snippets/1.git/info/refs?service=git-upload-pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized || resp.Header.Get("WWW-Authenticate") == "" {
+		t.Errorf("anonymous request = %d with challenge %q, want 401 with a challenge", resp.StatusCode, resp.Header.Get("WWW-Authenticate"))
+	}
+
+	snippet.Visibility = "public"
+	if output, err := clone(""); err != nil {
+		t.Errorf("anonymous clone of a public snippet failed: %v: %s", err, output)
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StorageHandler struct {
+	userRepo     *repository.UserRepository
+	storageRepo  *repository.StorageRepository
+	auditRepo    *repository.AuditRepository
+	quotaService *quota.Service
+}
+
+func NewStorageHandler(userRepo *repository.UserRepository, storageRepo *repository.StorageRepository, auditRepo *repository.AuditRepository, quotaService *quota.Service) *StorageHandler {
+	return &StorageHandler{
+		userRepo:     userRepo,
+		storageRepo:  storageRepo,
+		auditRepo:    auditRepo,
+		quotaService: quotaService,
+	}
+}
+
+// StorageLimitRequest sets the limits of a user in bytes. Omitted limits fall
+// back to the server defaults and zero means unlimited.
+type StorageLimitRequest struct {
+	Quota           *int64 `json:"quota" binding:"omitempty,min=0"`
+	RepositoryLimit *int64 `json:"repository_limit" binding:"omitempty,min=0"`
+}
+
+type namespaceUsage struct {
+	Username string `json:"username"`
+	*quota.Usage
+}
+
+// GetUserUsage reports the storage used by the current user's repositories.
+func (h *StorageHandler) GetUserUsage(c *gin.Context) {
+	usage, err := h.quotaService.Usage(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute storage usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, namespaceUsage{Username: c.GetString("username"), Usage: usage})
+}
+
+// ListUsage reports the storage of every user who owns repositories or has
+// overridden limits, largest first.
+func (h *StorageHandler) ListUsage(c *gin.Context) {
+	repos, err := h.storageRepo.FindUsage(0)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute storage usage"})
+		return
+	}
+	limits, err := h.storageRepo.FindLimits()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load storage limits"})
+		return
+	}
+
+	userIDs := make(map[uint]bool)
+	for _, repo := range repos {
+		userIDs[repo.OwnerID] = true
+	}
+	for _, limit := range limits {
+		userIDs[limit.UserID] = true
+	}
+
+	namespaces := make([]namespaceUsage, 0, len(userIDs))
+	for userID := range userIDs {
+		user, err := h.userRepo.FindByID(userID)
+		if err != nil {
+			continue
+		}
+		usage, err := h.quotaService.Usage(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute storage usage"})
+			return
+		}
+		namespaces = append(namespaces, namespaceUsage{Username: user.Username, Usage: usage})
+	}
+	sort.Slice(namespaces, func(i, j int) bool {
+		if namespaces[i].Used != namespaces[j].Used {
+			return namespaces[i].Used > namespaces[j].Used
+		}
+		return namespaces[i].Username < namespaces[j].Username
+	})
+
+	c.JSON(http.StatusOK, gin.H{"defaults": h.quotaService.Defaults(), "namespaces": namespaces})
+}
+
+// GetNamespaceUsage reports the storage of one user.
+func (h *StorageHandler) GetNamespaceUsage(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	usage, err := h.quotaService.Usage(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute storage usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, namespaceUsage{Username: user.Username, Usage: usage})
+}
+
+// SetLimits overrides the storage limits of a user.
+func (h *StorageHandler) SetLimits(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	var req StorageLimitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit := &models.StorageLimit{UserID: user.ID, Quota: req.Quota, RepositoryLimit: req.RepositoryLimit}
+	if err := h.storageRepo.SaveLimit(limit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save storage limits"})
+		return
+	}
+	h.audit(c, "storage_limit.updated", user, fmt.Sprintf("quota=%s repository_limit=%s", formatLimit(req.Quota), formatLimit(req.RepositoryLimit)))
+
+	h.GetNamespaceUsage(c)
+}
+
+// DeleteLimits returns a user to the server default limits.
+func (h *StorageHandler) DeleteLimits(c *gin.Context) {
+	user, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.storageRepo.DeleteLimit(user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete storage limits"})
+		return
+	}
+	h.audit(c, "storage_limit.removed", user, "")
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *StorageHandler) loadUser(c *gin.Context) (*models.User, bool) {
+	user, err := h.userRepo.FindByUsername(c.Param("username"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load user"})
+		return nil, false
+	}
+	return user, true
+}
+
+func (h *StorageHandler) audit(c *gin.Context, action string, user *models.User, details string) {
+	event := &models.AuditEvent{
+		ActorID:    c.GetUint("user_id"),
+		Action:     action,
+		TargetType: "user",
+		TargetID:   user.ID,
+		Details:    details,
+	}
+	if err := h.auditRepo.Create(event); err != nil {
+		fmt.Printf("Warning: Failed to record audit event: %v\n", err)
+	}
+}
+
+func formatLimit(limit *int64) string {
+	if limit == nil {
+		return "default"
+	}
+	if *limit == 0 {
+		return "unlimited"
+	}
+	return quota.FormatSize(*limit)
+}
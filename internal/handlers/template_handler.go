@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"gitlab-tool/internal/templates"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TemplateHandler lists the .gitignore and license templates new
+// repositories can be seeded with.
+type TemplateHandler struct{}
+
+func NewTemplateHandler() *TemplateHandler {
+	return &TemplateHandler{}
+}
+
+func (h *TemplateHandler) ListGitignoreTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, templates.GitignoreTemplates())
+}
+
+func (h *TemplateHandler) GetGitignoreTemplate(c *gin.Context) {
+	name, content, err := templates.Gitignore(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"name": name, "source": string(content)})
+}
+
+func (h *TemplateHandler) ListLicenses(c *gin.Context) {
+	c.JSON(http.StatusOK, templates.Licenses())
+}
+
+// GetLicense returns a license as it would be committed by the current user
+// today.
+func (h *TemplateHandler) GetLicense(c *gin.Context) {
+	vars := templates.Vars{Owner: c.GetString("username")}
+	license, content, err := templates.LicenseText(c.Param("key"), time.Now().Year(), vars)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "License not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"key": license.Key, "name": license.Name, "body": string(content)})
+}
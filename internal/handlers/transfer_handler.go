@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TransferHandler renames repositories and moves them between owners. Both
+// leave a redirect behind so old clone URLs keep working.
+type TransferHandler struct {
+	repoRepo     *repository.RepositoryRepository
+	userRepo     *repository.UserRepository
+	transferRepo *repository.TransferRepository
+	gitService   *git.Service
+	locks        *jobs.Locks
+}
+
+func NewTransferHandler(repoRepo *repository.RepositoryRepository, userRepo *repository.UserRepository, transferRepo *repository.TransferRepository, gitService *git.Service, locks *jobs.Locks) *TransferHandler {
+	return &TransferHandler{
+		repoRepo:     repoRepo,
+		userRepo:     userRepo,
+		transferRepo: transferRepo,
+		gitService:   gitService,
+		locks:        locks,
+	}
+}
+
+type RenameRepositoryRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type TransferRepositoryRequest struct {
+	NewOwner string `json:"new_owner" binding:"required"`
+}
+
+func (h *TransferHandler) RenameRepository(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	if !canWriteRepository(repo, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var req RenameRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateRepositoryName(req.Name); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.Name == repo.Name {
+		c.JSON(http.StatusOK, repo)
+		return
+	}
+
+	if !h.relocate(c, repo, &repo.Owner, req.Name) {
+		return
+	}
+
+	renamed, err := h.repoRepo.FindByID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, renamed)
+}
+
+// TransferRepository offers the repository to another user. Nothing moves
+// until that user accepts.
+func (h *TransferHandler) TransferRepository(c *gin.Context) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if !canWriteRepository(repo, userID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	var req TransferRepositoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := h.userRepo.FindByUsername(req.NewOwner)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if target.ID == repo.OwnerID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Repository already belongs to this user"})
+		return
+	}
+
+	if _, err := h.repoRepo.FindByUsernameAndName(target.Username, repo.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Target user already has a repository with this name"})
+		return
+	}
+
+	if _, err := h.transferRepo.FindByRepositoryID(repo.ID); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A transfer is already pending for this repository"})
+		return
+	}
+
+	transfer := &models.RepositoryTransfer{
+		RepositoryID: repo.ID,
+		FromUserID:   userID,
+		ToUserID:     target.ID,
+	}
+	if err := h.transferRepo.Create(transfer); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+		return
+	}
+
+	created, err := h.transferRepo.FindByID(transfer.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load transfer"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// ListTransfers returns pending transfers offered to or by the current user.
+func (h *TransferHandler) ListTransfers(c *gin.Context) {
+	transfers, err := h.transferRepo.FindForUser(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+		return
+	}
+
+	c.JSON(http.StatusOK, transfers)
+}
+
+func (h *TransferHandler) AcceptTransfer(c *gin.Context) {
+	transfer, ok := h.loadTransfer(c)
+	if !ok {
+		return
+	}
+
+	if transfer.ToUserID != c.GetUint("user_id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	// The repository may have been renamed since the transfer was offered
+	repo, err := h.repoRepo.FindByID(transfer.RepositoryID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return
+	}
+
+	if !h.relocate(c, repo, &transfer.ToUser, repo.Name) {
+		return
+	}
+
+	moved, err := h.repoRepo.FindByID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, moved)
+}
+
+// DeclineTransfer removes a pending transfer. The target declines it, the
+// current owner cancels it.
+func (h *TransferHandler) DeclineTransfer(c *gin.Context) {
+	transfer, ok := h.loadTransfer(c)
+	if !ok {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if transfer.ToUserID != userID && transfer.FromUserID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if err := h.transferRepo.Delete(transfer.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete transfer"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer cancelled"})
+}
+
+func (h *TransferHandler) loadTransfer(c *gin.Context) (*models.RepositoryTransfer, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid transfer ID"})
+		return nil, false
+	}
+
+	transfer, err := h.transferRepo.FindByID(uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return nil, false
+	}
+
+	return transfer, true
+}
+
+// relocate moves the repository on disk and then in the database, moving it
+// back on disk if the database update fails. It holds the exclusive lock of
+// the repository throughout, so running pushes and maintenance finish first,
+// and refuses if the repository has been renamed or transferred since repo
+// was loaded. On failure the error response has already been written.
+func (h *TransferHandler) relocate(c *gin.Context, repo *models.Repository, newOwner *models.User, newName string) bool {
+	unlock := h.locks.Lock(repo.ID)
+	defer unlock()
+
+	current, err := h.repoRepo.FindByID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return false
+	}
+	if current.OwnerID != repo.OwnerID || current.Name != repo.Name {
+		c.JSON(http.StatusConflict, gin.H{"error": "Repository was renamed or transferred in the meantime"})
+		return false
+	}
+	repo = current
+
+	if _, err := h.repoRepo.FindByUsernameAndName(newOwner.Username, newName); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Repository already exists"})
+		return false
+	}
+
+	oldOwner := repo.Owner.Username
+	if err := h.gitService.MoveRepository(oldOwner, repo.Name, newOwner.Username, newName); err != nil {
+		if errors.Is(err, git.ErrRepositoryExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Repository already exists"})
+			return false
+		}
+		if errors.Is(err, git.ErrRepositoryNotFound) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Repository is unavailable; ask an administrator to check its storage"})
+			return false
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to move repository"})
+		return false
+	}
+
+	if err := h.repoRepo.Relocate(repo, newOwner, newName); err != nil {
+		if err := h.gitService.MoveRepository(newOwner.Username, newName, oldOwner, repo.Name); err != nil {
+			fmt.Printf("Warning: Failed to move repository back after database error: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update repository"})
+		return false
+	}
+
+	return true
+}
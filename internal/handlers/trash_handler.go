@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+	"gitlab-tool/internal/trash"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrashHandler lists and restores deleted repositories. Deletion itself is
+// RepositoryHandler.DeleteRepository and purging is done by trash.Purger.
+type TrashHandler struct {
+	repoRepo   *repository.RepositoryRepository
+	gitService *git.Service
+	purger     *trash.Purger
+	locks      *jobs.Locks
+}
+
+func NewTrashHandler(repoRepo *repository.RepositoryRepository, gitService *git.Service, purger *trash.Purger, locks *jobs.Locks) *TrashHandler {
+	return &TrashHandler{
+		repoRepo:   repoRepo,
+		gitService: gitService,
+		purger:     purger,
+		locks:      locks,
+	}
+}
+
+type TrashedRepository struct {
+	models.Repository
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	repos, err := h.repoRepo.FindTrashedByOwnerID(c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deleted repositories"})
+		return
+	}
+
+	trashed := make([]TrashedRepository, len(repos))
+	for i := range repos {
+		trashed[i] = TrashedRepository{Repository: repos[i], PurgeAt: h.purger.Expiry(&repos[i])}
+	}
+
+	c.JSON(http.StatusOK, trashed)
+}
+
+func (h *TrashHandler) RestoreRepository(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	// Concurrent restores of the same repository must not both move it
+	unlock := h.locks.Lock(uint(id))
+	defer unlock()
+
+	repo, err := h.repoRepo.FindTrashedByID(uint(id))
+	if err != nil || repo.OwnerID != c.GetUint("user_id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Deleted repository not found"})
+		return
+	}
+
+	if time.Now().After(h.purger.Expiry(repo)) {
+		c.JSON(http.StatusGone, gin.H{"error": "Repository can no longer be restored"})
+		return
+	}
+
+	if _, err := h.repoRepo.FindByUsernameAndName(repo.Owner.Username, repo.Name); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "A repository with this name already exists"})
+		return
+	}
+
+	if err := h.gitService.RestoreRepository(repo.ID, repo.Owner.Username, repo.Name); err != nil {
+		if errors.Is(err, git.ErrRepositoryExists) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A repository with this name already exists"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore repository files"})
+		return
+	}
+
+	if err := h.repoRepo.Restore(repo.ID); err != nil {
+		if err := h.gitService.TrashRepository(repo.ID, repo.Owner.Username, repo.Name); err != nil {
+			fmt.Printf("Warning: Failed to move repository back to trash: %v\n", err)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore repository"})
+		return
+	}
+
+	restored, err := h.repoRepo.FindByID(repo.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load repository"})
+		return
+	}
+
+	c.JSON(http.StatusOK, restored)
+}
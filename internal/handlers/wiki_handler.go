@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/markdown"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WikiHandler struct {
+	repoRepo   *repository.RepositoryRepository
+	userRepo   *repository.UserRepository
+	gitService *git.Service
+	// locks keeps maintenance away from wikis that are being edited
+	locks *jobs.Locks
+}
+
+func NewWikiHandler(repoRepo *repository.RepositoryRepository, userRepo *repository.UserRepository, gitService *git.Service, locks *jobs.Locks) *WikiHandler {
+	return &WikiHandler{
+		repoRepo:   repoRepo,
+		userRepo:   userRepo,
+		gitService: gitService,
+		locks:      locks,
+	}
+}
+
+type CreateWikiPageRequest struct {
+	Title   string `json:"title" binding:"required"`
+	Content string `json:"content"`
+	Message string `json:"message"`
+}
+
+type UpdateWikiPageRequest struct {
+	Content string `json:"content"`
+	Message string `json:"message"`
+}
+
+type DeleteWikiPageRequest struct {
+	Message string `json:"message"`
+}
+
+type WikiPageResponse struct {
+	*git.WikiPage
+	HTML string `json:"html"`
+}
+
+func (h *WikiHandler) ListPages(c *gin.Context) {
+	repo, ok := h.loadWikiRepository(c)
+	if !ok {
+		return
+	}
+
+	pages, err := h.gitService.ListWikiPages(repo.Owner.Username, repo.Name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list wiki pages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, pages)
+}
+
+func (h *WikiHandler) GetPage(c *gin.Context) {
+	repo, ok := h.loadWikiRepository(c)
+	if !ok {
+		return
+	}
+
+	page, err := h.gitService.ReadWikiPage(repo.Owner.Username, repo.Name, c.Param("page"))
+	if err != nil {
+		writeWikiError(c, err, "Failed to read wiki page")
+		return
+	}
+
+	ctx := markdownContext(repo, "", "")
+	ctx.Wiki = true
+	html, err := markdown.Render([]byte(page.Content), ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render wiki page"})
+		return
+	}
+
+	c.JSON(http.StatusOK, WikiPageResponse{WikiPage: page, HTML: html})
+}
+
+func (h *WikiHandler) CreatePage(c *gin.Context) {
+	repo, ok := h.loadWikiRepository(c)
+	if !ok {
+		return
+	}
+
+	if !canWriteRepository(repo, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if rejectArchived(c, repo) {
+		return
+	}
+
+	var req CreateWikiPageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	author, ok := currentSignature(c, h.userRepo)
+	if !ok {
+		return
+	}
+
+	unlock := h.locks.RLock(repo.ID)
+	defer unlock()
+	page, err := h.gitService.SaveWikiPage(repo.Owner.Username, repo.Name, req.Title, req.Content, req.Message, author, true)
+	if err != nil {
+		writeWikiError(c, err, "Failed to create wiki page")
+		return
+	}
+
+	c.JSON(http.StatusCreated, page)
+}
+
+func (h *WikiHandler) UpdatePage(c *gin.Context) {
+	repo, ok := h.loadWikiRepository(c)
+	if !ok {
+		return
+	}
+
+	if !canWriteRepository(repo, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if rejectArchived(c, repo) {
+		return
+	}
+
+	var req UpdateWikiPageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	author, ok := currentSignature(c, h.userRepo)
+	if !ok {
+		return
+	}
+
+	unlock := h.locks.RLock(repo.ID)
+	defer unlock()
+	page, err := h.gitService.SaveWikiPage(repo.Owner.Username, repo.Name, c.Param("page"), req.Content, req.Message, author, false)
+	if err != nil {
+		writeWikiError(c, err, "Failed to update wiki page")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *WikiHandler) DeletePage(c *gin.Context) {
+	repo, ok := h.loadWikiRepository(c)
+	if !ok {
+		return
+	}
+
+	if !canWriteRepository(repo, c.GetUint("user_id")) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if rejectArchived(c, repo) {
+		return
+	}
+
+	// The commit message is optional, so an empty body is fine
+	var req DeleteWikiPageRequest
+	_ = c.ShouldBindJSON(&req)
+
+	author, ok := currentSignature(c, h.userRepo)
+	if !ok {
+		return
+	}
+
+	unlock := h.locks.RLock(repo.ID)
+	defer unlock()
+	if err := h.gitService.DeleteWikiPage(repo.Owner.Username, repo.Name, c.Param("page"), req.Message, author); err != nil {
+		writeWikiError(c, err, "Failed to delete wiki page")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wiki page deleted successfully"})
+}
+
+// loadWikiRepository is loadRepository for wiki routes, which are hidden
+// when the wiki feature is turned off for the repository.
+func (h *WikiHandler) loadWikiRepository(c *gin.Context) (*models.Repository, bool) {
+	repo, ok := loadRepository(c, h.repoRepo)
+	if !ok {
+		return nil, false
+	}
+
+	if !repo.HasWiki {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wiki is disabled"})
+		return nil, false
+	}
+
+	return repo, true
+}
+
+func writeWikiError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, git.ErrInvalidPageName):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wiki page name"})
+	case errors.Is(err, git.ErrPageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wiki page not found"})
+	case errors.Is(err, git.ErrPageExists):
+		c.JSON(http.StatusConflict, gin.H{"error": "Wiki page already exists"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
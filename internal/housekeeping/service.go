@@ -0,0 +1,246 @@
+// Package housekeeping keeps the bare repositories on disk packed and
+// indexed, based on how often they are pushed to and how many loose objects
+// they accumulate.
+package housekeeping
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+)
+
+// Maintenance tasks, from cheapest to most thorough.
+const (
+	TaskIncremental = "incremental"
+	TaskFull        = "full"
+)
+
+const (
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// runTimeout bounds a single maintenance run of a repository.
+const runTimeout = time.Hour
+
+// busyRetry is how long maintenance of a repository that is being written to
+// waits before trying again.
+const busyRetry = time.Minute
+
+// Policy decides when repositories need maintenance. A zero threshold never
+// triggers.
+type Policy struct {
+	// RepackPushes is the number of pushes after which loose objects are
+	// packed incrementally
+	RepackPushes int
+	// GCPushes is the number of pushes after which the repository is fully
+	// repacked
+	GCPushes int
+	// LooseObjects triggers an incremental repack once that many loose
+	// objects have accumulated
+	LooseObjects int
+	// Packs triggers a full repack once incremental repacks have left that
+	// many packs
+	Packs int
+}
+
+// Decide returns the task a repository needs, or "" when it needs none.
+func (p Policy) Decide(record *models.RepositoryHousekeeping, stats git.ObjectStats) string {
+	switch {
+	case reached(record.PushesSinceGC, p.GCPushes), reached(stats.Packs, p.Packs):
+		return TaskFull
+	case reached(record.PushesSinceRepack, p.RepackPushes), reached(stats.LooseObjects, p.LooseObjects):
+		return TaskIncremental
+	}
+	return ""
+}
+
+func reached(value, threshold int) bool {
+	return threshold > 0 && value >= threshold
+}
+
+type Service struct {
+	repoRepo         *repository.RepositoryRepository
+	housekeepingRepo *repository.HousekeepingRepository
+	gitService       *git.Service
+	policy           Policy
+	locks            *jobs.Locks
+	runs             *jobs.Coalescer
+
+	mu        sync.Mutex
+	requested map[uint]string
+	retrying  map[uint]bool
+}
+
+// NewService creates the housekeeping service. locks are the per-repository
+// locks shared with everything that writes objects: they hold the shared
+// lock while writing, and maintenance only runs while nobody does.
+func NewService(repoRepo *repository.RepositoryRepository, housekeepingRepo *repository.HousekeepingRepository, gitService *git.Service, locks *jobs.Locks, policy Policy) *Service {
+	return &Service{
+		repoRepo:         repoRepo,
+		housekeepingRepo: housekeepingRepo,
+		gitService:       gitService,
+		policy:           policy,
+		locks:            locks,
+		runs:             jobs.NewCoalescer(),
+		requested:        make(map[uint]string),
+		retrying:         make(map[uint]bool),
+	}
+}
+
+// Schedule counts a push to a repository and maintains it in the background
+// if the push takes it over a threshold.
+func (s *Service) Schedule(repo *models.Repository) {
+	if err := s.housekeepingRepo.CountPush(repo.ID); err != nil {
+		fmt.Printf("Warning: Failed to count push to repository %d: %v\n", repo.ID, err)
+	}
+	s.runs.Run(repo.ID, func() { s.maintain(repo.ID) })
+}
+
+// Trigger runs a task on a repository in the background regardless of the
+// policy. Requests that arrive while a run is in progress are folded into
+// one follow-up run of the most thorough task requested.
+func (s *Service) Trigger(repoID uint, task string) {
+	s.mu.Lock()
+	if s.requested[repoID] != TaskFull {
+		s.requested[repoID] = task
+	}
+	s.mu.Unlock()
+	s.runs.Run(repoID, func() { s.maintain(repoID) })
+}
+
+// Start checks every repository against the policy now and then once per
+// interval, until the process exits. This catches repositories that gather
+// loose objects without being pushed to, such as through web edits.
+func (s *Service) Start(interval time.Duration) {
+	go func() {
+		for {
+			s.maintainAll()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// FailInterrupted marks runs left unfinished by a previous process as failed.
+func (s *Service) FailInterrupted() {
+	records, err := s.housekeepingRepo.FindRunning()
+	if err != nil {
+		fmt.Printf("Warning: Failed to load interrupted housekeeping runs: %v\n", err)
+		return
+	}
+	for _, record := range records {
+		if err := s.housekeepingRepo.Finish(record.RepositoryID, StatusFailed, "interrupted by a server restart"); err != nil {
+			fmt.Printf("Warning: Failed to update housekeeping of repository %d: %v\n", record.RepositoryID, err)
+		}
+	}
+}
+
+func (s *Service) maintainAll() {
+	repos, err := s.repoRepo.FindAll()
+	if err != nil {
+		fmt.Printf("Warning: Failed to list repositories for housekeeping: %v\n", err)
+		return
+	}
+	for _, repo := range repos {
+		s.maintain(repo.ID)
+	}
+}
+
+// maintain runs the requested task on a repository and its wiki, or the one
+// the policy calls for.
+func (s *Service) maintain(repoID uint) {
+	s.mu.Lock()
+	task := s.requested[repoID]
+	delete(s.requested, repoID)
+	s.mu.Unlock()
+
+	// The repository may have been deleted since the run was scheduled
+	repo, err := s.repoRepo.FindByID(repoID)
+	if err != nil {
+		return
+	}
+	repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+
+	// Waiting for the writers to finish would hold up every write that
+	// arrives after it, so come back later instead
+	unlock, ok := s.locks.TryLock(repo.ID)
+	if !ok {
+		s.retry(repo.ID, task)
+		return
+	}
+	defer unlock()
+
+	if task == "" {
+		record, err := s.housekeepingRepo.Find(repo.ID)
+		if err != nil {
+			fmt.Printf("Warning: Failed to load housekeeping of repository %d: %v\n", repo.ID, err)
+			return
+		}
+		stats, err := s.gitService.ObjectStats(repoPath)
+		if err != nil {
+			fmt.Printf("Warning: Failed to inspect repository %d: %v\n", repo.ID, err)
+			return
+		}
+		if task = s.policy.Decide(record, stats); task == "" {
+			return
+		}
+	}
+
+	if err := s.housekeepingRepo.Start(repo.ID, task, task == TaskFull); err != nil {
+		fmt.Printf("Warning: Failed to update housekeeping of repository %d: %v\n", repo.ID, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), runTimeout)
+	defer cancel()
+
+	paths := []string{repoPath}
+	if s.gitService.WikiExists(repo.Owner.Username, repo.Name) {
+		paths = append(paths, s.gitService.GetWikiPath(repo.Owner.Username, repo.Name))
+	}
+	status, message := StatusSuccess, ""
+	for _, path := range paths {
+		if err := s.run(ctx, path, task); err != nil {
+			fmt.Printf("Warning: Housekeeping of repository %d failed: %v\n", repo.ID, err)
+			status, message = StatusFailed, err.Error()
+			break
+		}
+	}
+	if err := s.housekeepingRepo.Finish(repo.ID, status, message); err != nil {
+		fmt.Printf("Warning: Failed to update housekeeping of repository %d: %v\n", repo.ID, err)
+	}
+}
+
+// retry puts back the task of a repository that was busy and maintains it
+// again after busyRetry. Only one retry per repository is pending at a time.
+func (s *Service) retry(repoID uint, task string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if task != "" && s.requested[repoID] != TaskFull {
+		s.requested[repoID] = task
+	}
+	if s.retrying[repoID] {
+		return
+	}
+	s.retrying[repoID] = true
+	time.AfterFunc(busyRetry, func() {
+		s.mu.Lock()
+		delete(s.retrying, repoID)
+		s.mu.Unlock()
+		s.runs.Run(repoID, func() { s.maintain(repoID) })
+	})
+}
+
+func (s *Service) run(ctx context.Context, repoPath, task string) error {
+	if task == TaskFull {
+		return s.gitService.GarbageCollect(ctx, repoPath)
+	}
+	return s.gitService.RepackIncremental(ctx, repoPath)
+}
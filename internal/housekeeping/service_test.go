@@ -0,0 +1,34 @@
+package housekeeping
+
+import (
+	"testing"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/models"
+)
+
+func TestPolicyDecide(t *testing.T) {
+	policy := Policy{RepackPushes: 10, GCPushes: 100, LooseObjects: 1000, Packs: 50}
+	tests := []struct {
+		name   string
+		record models.RepositoryHousekeeping
+		stats  git.ObjectStats
+		want   string
+	}{
+		{"fresh repository", models.RepositoryHousekeeping{}, git.ObjectStats{}, ""},
+		{"few pushes", models.RepositoryHousekeeping{PushesSinceRepack: 9, PushesSinceGC: 9}, git.ObjectStats{LooseObjects: 999}, ""},
+		{"repack pushes", models.RepositoryHousekeeping{PushesSinceRepack: 10, PushesSinceGC: 10}, git.ObjectStats{}, TaskIncremental},
+		{"loose objects", models.RepositoryHousekeeping{}, git.ObjectStats{LooseObjects: 1000}, TaskIncremental},
+		{"gc pushes", models.RepositoryHousekeeping{PushesSinceRepack: 10, PushesSinceGC: 100}, git.ObjectStats{}, TaskFull},
+		{"too many packs", models.RepositoryHousekeeping{}, git.ObjectStats{LooseObjects: 1000, Packs: 50}, TaskFull},
+	}
+	for _, tt := range tests {
+		if got := policy.Decide(&tt.record, tt.stats); got != tt.want {
+			t.Errorf("%s: Decide = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if got := (Policy{}).Decide(&models.RepositoryHousekeeping{PushesSinceGC: 1000}, git.ObjectStats{LooseObjects: 1 << 20}); got != "" {
+		t.Errorf("zero policy Decide = %q, want no task", got)
+	}
+}
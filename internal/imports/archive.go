@@ -0,0 +1,403 @@
+package imports
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"gitlab-tool/internal/models"
+)
+
+// ArchiveVersion is the format version written to project archives. Archives
+// with a newer version are rejected.
+const ArchiveVersion = 1
+
+// Entries of a project archive. The manifest comes first so it can be read
+// without unpacking the bundles.
+const (
+	manifestEntry   = "project.json"
+	repositoryEntry = "repository.bundle"
+	wikiEntry       = "wiki.bundle"
+	statusesEntry   = "commit_statuses.json"
+)
+
+// ErrInvalidArchive is returned for files that are not project archives.
+var ErrInvalidArchive = errors.New("invalid project archive")
+
+// ArchiveUser identifies a user across servers. On import users are matched
+// by username, then by email.
+type ArchiveUser struct {
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// Manifest describes the project in an archive.
+type Manifest struct {
+	Version    int             `json:"version"`
+	ExportedAt time.Time       `json:"exported_at"`
+	Owner      ArchiveUser     `json:"owner"`
+	Project    ProjectSettings `json:"project"`
+}
+
+// ProjectSettings are the repository settings carried by an archive.
+type ProjectSettings struct {
+	Name             string   `json:"name"`
+	Description      string   `json:"description"`
+	Visibility       string   `json:"visibility"`
+	Topics           []string `json:"topics"`
+	Homepage         string   `json:"homepage"`
+	Archived         bool     `json:"archived"`
+	IsTemplate       bool     `json:"is_template"`
+	HasIssues        bool     `json:"has_issues"`
+	HasWiki          bool     `json:"has_wiki"`
+	HasMergeRequests bool     `json:"has_merge_requests"`
+	MergeStrategies  []string `json:"merge_strategies"`
+	DefaultBranch    string   `json:"default_branch"`
+}
+
+// ArchivedStatus is a commit status with its creator identified portably.
+type ArchivedStatus struct {
+	SHA         string      `json:"sha"`
+	State       string      `json:"state"`
+	Context     string      `json:"context"`
+	Description string      `json:"description"`
+	TargetURL   string      `json:"target_url"`
+	Creator     ArchiveUser `json:"creator"`
+	CreatedAt   time.Time   `json:"created_at"`
+}
+
+// Export writes a project archive of repo to w: a gzipped tarball holding the
+// manifest, bundles of the repository and wiki, and the commit statuses.
+// repo must be loaded with its owner.
+func (s *Service) Export(ctx context.Context, repo *models.Repository, w io.Writer) error {
+	statuses, err := s.statusRepo.FindByRepositoryID(repo.ID)
+	if err != nil {
+		return err
+	}
+	return s.exportArchive(ctx, repo, statuses, w)
+}
+
+// exportArchive writes the archive of repo with the given commit statuses,
+// which must be loaded with their creators.
+func (s *Service) exportArchive(ctx context.Context, repo *models.Repository, statuses []models.CommitStatus, w io.Writer) error {
+	repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	defaultBranch, err := s.gitService.DefaultBranch(repoPath)
+	if err != nil {
+		return err
+	}
+
+	tempDir, err := os.MkdirTemp("", "export-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tempDir)
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest := Manifest{
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+		Owner:      archiveUser(&repo.Owner),
+		Project: ProjectSettings{
+			Name:             repo.Name,
+			Description:      repo.Description,
+			Visibility:       repo.Visibility,
+			Topics:           repo.Topics,
+			Homepage:         repo.Homepage,
+			Archived:         repo.Archived,
+			IsTemplate:       repo.IsTemplate,
+			HasIssues:        repo.HasIssues,
+			HasWiki:          repo.HasWiki,
+			HasMergeRequests: repo.HasMergeRequests,
+			MergeStrategies:  repo.MergeStrategies,
+			DefaultBranch:    defaultBranch,
+		},
+	}
+	if err := writeJSONEntry(tw, manifestEntry, manifest); err != nil {
+		return err
+	}
+
+	bundles := []struct{ entry, repoPath string }{
+		{repositoryEntry, repoPath},
+		{wikiEntry, s.gitService.GetWikiPath(repo.Owner.Username, repo.Name)},
+	}
+	for _, bundle := range bundles {
+		if bundle.entry == wikiEntry && !s.gitService.WikiExists(repo.Owner.Username, repo.Name) {
+			continue
+		}
+		bundlePath := filepath.Join(tempDir, bundle.entry)
+		ok, err := s.gitService.CreateBundle(ctx, bundle.repoPath, bundlePath)
+		if err != nil {
+			return err
+		}
+		if ok {
+			if err := writeFileEntry(tw, bundle.entry, bundlePath); err != nil {
+				return err
+			}
+		}
+	}
+
+	archived := make([]ArchivedStatus, 0, len(statuses))
+	for _, status := range statuses {
+		archived = append(archived, ArchivedStatus{
+			SHA:         status.SHA,
+			State:       status.State,
+			Context:     status.Context,
+			Description: status.Description,
+			TargetURL:   status.TargetURL,
+			Creator:     archiveUser(&status.Creator),
+			CreatedAt:   status.CreatedAt,
+		})
+	}
+	if err := writeJSONEntry(tw, statusesEntry, archived); err != nil {
+		return err
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadManifest reads the manifest of a project archive.
+func ReadManifest(archivePath string) (*Manifest, error) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	tr := tar.NewReader(gz)
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestEntry {
+		return nil, ErrInvalidArchive
+	}
+
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, ErrInvalidArchive
+	}
+	if manifest.Version < 1 || manifest.Version > ArchiveVersion {
+		return nil, fmt.Errorf("unsupported project archive version %d", manifest.Version)
+	}
+	return &manifest, nil
+}
+
+// importArchive fills a repository, its wiki and its commit statuses from a
+// project archive.
+func (s *Service) importArchive(ctx context.Context, imp *models.RepositoryImport, repo *models.Repository, archivePath string) error {
+	users := map[ArchiveUser]uint{}
+	statuses, err := s.restoreArchive(ctx, repo, archivePath, func(user ArchiveUser) uint {
+		id, ok := users[user]
+		if !ok {
+			id = s.mapUser(user, imp.UserID)
+			users[user] = id
+		}
+		return id
+	})
+	if err != nil {
+		return err
+	}
+	for i := range statuses {
+		if err := s.statusRepo.Create(&statuses[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restoreArchive fills a repository and its wiki from a project archive,
+// keeping the default branch recorded in the manifest, and returns the
+// archived commit statuses with their creators mapped to local user IDs by
+// mapUser.
+func (s *Service) restoreArchive(ctx context.Context, repo *models.Repository, archivePath string, mapUser func(ArchiveUser) uint) ([]models.CommitStatus, error) {
+	tempDir, err := os.MkdirTemp("", "import-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := extractArchive(archivePath, tempDir, s.maxSize); err != nil {
+		return nil, err
+	}
+	var manifest Manifest
+	if data, err := os.ReadFile(filepath.Join(tempDir, manifestEntry)); err != nil || json.Unmarshal(data, &manifest) != nil {
+		return nil, ErrInvalidArchive
+	}
+
+	repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	if bundlePath := filepath.Join(tempDir, repositoryEntry); fileExists(bundlePath) {
+		if err := s.gitService.ImportBundle(ctx, repoPath, bundlePath); err != nil {
+			return nil, err
+		}
+		// The bundle only hints at the default branch, so HEAD is guessed;
+		// the manifest has the real one
+		if branch := manifest.Project.DefaultBranch; branch != "" {
+			branches, err := s.gitService.ListRefs(repoPath, "refs/heads/")
+			if err != nil {
+				return nil, err
+			}
+			if slices.Contains(branches, branch) {
+				if err := s.gitService.SetDefaultBranch(repoPath, branch); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if bundlePath := filepath.Join(tempDir, wikiEntry); fileExists(bundlePath) {
+		wikiPath := s.gitService.GetWikiPath(repo.Owner.Username, repo.Name)
+		if err := s.gitService.InitBareRepositoryAt(wikiPath, "main"); err != nil {
+			return nil, err
+		}
+		if err := s.gitService.ImportBundle(ctx, wikiPath, bundlePath); err != nil {
+			return nil, fmt.Errorf("wiki: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(tempDir, statusesEntry))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var archived []ArchivedStatus
+	if err := json.Unmarshal(data, &archived); err != nil {
+		return nil, ErrInvalidArchive
+	}
+	statuses := make([]models.CommitStatus, 0, len(archived))
+	for _, status := range archived {
+		statuses = append(statuses, models.CommitStatus{
+			RepositoryID: repo.ID,
+			SHA:          status.SHA,
+			State:        status.State,
+			Context:      status.Context,
+			Description:  status.Description,
+			TargetURL:    status.TargetURL,
+			CreatorID:    mapUser(status.Creator),
+			CreatedAt:    status.CreatedAt,
+		})
+	}
+	return statuses, nil
+}
+
+// mapUser finds the local user matching an archived one by username, then by
+// email, and falls back to the user running the import.
+func (s *Service) mapUser(user ArchiveUser, fallbackID uint) uint {
+	if user.Username != "" {
+		if local, err := s.userRepo.FindByUsername(user.Username); err == nil {
+			return local.ID
+		}
+	}
+	if user.Email != "" {
+		if local, err := s.userRepo.FindByEmail(user.Email); err == nil {
+			return local.ID
+		}
+	}
+	return fallbackID
+}
+
+// extractArchive unpacks the known entries of a project archive into dir,
+// failing once more than maxSize bytes have been written.
+func extractArchive(archivePath, dir string, maxSize int64) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return ErrInvalidArchive
+	}
+	tr := tar.NewReader(gz)
+	remaining := maxSize
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return ErrInvalidArchive
+		}
+		switch header.Name {
+		case manifestEntry, repositoryEntry, wikiEntry, statusesEntry:
+		default:
+			// Entries are never used as paths, so unknown names are
+			// harmless; skip them for forward compatibility
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return ErrInvalidArchive
+		}
+
+		out, err := os.Create(filepath.Join(dir, header.Name))
+		if err != nil {
+			return err
+		}
+		written, err := io.Copy(out, io.LimitReader(tr, remaining+1))
+		out.Close()
+		if err != nil {
+			return ErrInvalidArchive
+		}
+		remaining -= written
+		if remaining < 0 {
+			return ErrTooLarge
+		}
+	}
+}
+
+func archiveUser(user *models.User) ArchiveUser {
+	return ArchiveUser{Username: user.Username, Email: user.Email}
+}
+
+func writeJSONEntry(tw *tar.Writer, name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+func writeFileEntry(tw *tar.Writer, name, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
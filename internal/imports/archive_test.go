@@ -0,0 +1,196 @@
+package imports
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/models"
+)
+
+// writeArchive writes a project archive with the given entries, in order.
+func writeArchive(t *testing.T, entries [][2]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "project.tar.gz")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, entry := range entries {
+		header := &tar.Header{Name: entry[0], Mode: 0644, Size: int64(len(entry[1]))}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(entry[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadManifest(t *testing.T) {
+	path := writeArchive(t, [][2]string{
+		{manifestEntry, `{"version": 1, "project": {"name": "demo", "default_branch": "main"}}`},
+		{statusesEntry, `[]`},
+	})
+	manifest, err := ReadManifest(path)
+	if err != nil {
+		t.Fatalf("ReadManifest failed: %v", err)
+	}
+	if manifest.Project.Name != "demo" || manifest.Project.DefaultBranch != "main" {
+		t.Errorf("project = %+v", manifest.Project)
+	}
+
+	for name, entries := range map[string][][2]string{
+		"manifest not first": {{statusesEntry, `[]`}, {manifestEntry, `{"version": 1}`}},
+		"newer version":      {{manifestEntry, `{"version": 2}`}},
+		"not json":           {{manifestEntry, `demo`}},
+	} {
+		if _, err := ReadManifest(writeArchive(t, entries)); err == nil {
+			t.Errorf("%s: ReadManifest succeeded", name)
+		}
+	}
+
+	notArchive := filepath.Join(t.TempDir(), "plain.txt")
+	os.WriteFile(notArchive, []byte("plain"), 0644)
+	if _, err := ReadManifest(notArchive); !errors.Is(err, ErrInvalidArchive) {
+		t.Errorf("ReadManifest of a plain file = %v, want ErrInvalidArchive", err)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	path := writeArchive(t, [][2]string{
+		{manifestEntry, `{"version": 1}`},
+		{"../escape", "ignored"},
+		{statusesEntry, `[]`},
+	})
+	dir := t.TempDir()
+	if err := extractArchive(path, dir, 1<<20); err != nil {
+		t.Fatalf("extractArchive failed: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 2 {
+		t.Errorf("extracted %d entries, want 2", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "escape")); !os.IsNotExist(err) {
+		t.Error("entry outside the known names was extracted")
+	}
+
+	if err := extractArchive(path, t.TempDir(), 10); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("extractArchive over the limit = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestArchiveRoundTrip(t *testing.T) {
+	source := &Service{gitService: git.NewService(t.TempDir()), maxSize: 1 << 30}
+	gitService := source.gitService
+	author := git.Signature{Name: "alice", Email: "alice@example.com"}
+	if err := gitService.InitBareRepository("alice", "project"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	repoPath := gitService.GetRepositoryPath("alice", "project")
+	trunk, err := gitService.CommitFiles(repoPath, git.CommitOptions{
+		Branch: "main", Message: "Initial commit", Author: author,
+		Files: []git.FileChange{{Path: "README.md", Content: []byte("hello")}},
+	})
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+	// With both branches on the same commit the bundle cannot tell which
+	// one is the default
+	branch := exec.Command("git", "branch", "trunk", "main")
+	branch.Dir = repoPath
+	if output, err := branch.CombinedOutput(); err != nil {
+		t.Fatalf("git branch failed: %v: %s", err, output)
+	}
+	if err := gitService.SetDefaultBranch(repoPath, "trunk"); err != nil {
+		t.Fatalf("SetDefaultBranch failed: %v", err)
+	}
+	wikiPath := gitService.GetWikiPath("alice", "project")
+	if err := gitService.InitBareRepositoryAt(wikiPath, "main"); err != nil {
+		t.Fatalf("InitBareRepositoryAt failed: %v", err)
+	}
+	if _, err := gitService.CommitFiles(wikiPath, git.CommitOptions{
+		Branch: "main", Message: "Home", Author: author,
+		Files: []git.FileChange{{Path: "Home.md", Content: []byte("# Home")}},
+	}); err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+
+	repo := &models.Repository{ID: 1, Name: "project", Owner: models.User{Username: "alice", Email: "alice@example.com"}}
+	statuses := []models.CommitStatus{
+		{SHA: trunk, State: "success", Context: "ci", Creator: models.User{Username: "ci-bot", Email: "ci@example.com"}},
+		{SHA: trunk, State: "failure", Context: "lint", Creator: models.User{Username: "gone", Email: "bob@example.com"}},
+		{SHA: trunk, State: "pending", Context: "deploy", Creator: models.User{Username: "stranger", Email: "x@example.com"}},
+	}
+	archivePath := filepath.Join(t.TempDir(), "project.tar.gz")
+	file, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.exportArchive(context.Background(), repo, statuses, file); err != nil {
+		t.Fatalf("exportArchive failed: %v", err)
+	}
+	file.Close()
+
+	// Restore as carol/copy on another server, where ci-bot exists by name
+	// and bob only by email
+	target := &Service{gitService: git.NewService(t.TempDir()), maxSize: 1 << 30}
+	if err := target.gitService.InitBareRepository("carol", "copy"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	local := map[string]uint{"ci-bot": 10, "bob@example.com": 11}
+	copied := &models.Repository{ID: 2, Name: "copy", Owner: models.User{Username: "carol"}}
+	restored, err := target.restoreArchive(context.Background(), copied, archivePath, func(user ArchiveUser) uint {
+		if id, ok := local[user.Username]; ok {
+			return id
+		}
+		if id, ok := local[user.Email]; ok {
+			return id
+		}
+		return 99
+	})
+	if err != nil {
+		t.Fatalf("restoreArchive failed: %v", err)
+	}
+
+	copyPath := target.gitService.GetRepositoryPath("carol", "copy")
+	if branch, _ := target.gitService.DefaultBranch(copyPath); branch != "trunk" {
+		t.Errorf("default branch = %q, want trunk", branch)
+	}
+	if branches, _ := target.gitService.ListRefs(copyPath, "refs/heads/"); len(branches) != 2 {
+		t.Errorf("branches = %v, want main and trunk", branches)
+	}
+	if !target.gitService.WikiExists("carol", "copy") {
+		t.Fatal("wiki was not restored")
+	}
+	home, err := target.gitService.ReadFile(target.gitService.GetWikiPath("carol", "copy"), "main", "Home.md")
+	if err != nil || string(home) != "# Home" {
+		t.Errorf("wiki Home.md = %q, %v", home, err)
+	}
+
+	if len(restored) != 3 {
+		t.Fatalf("restored %d statuses, want 3", len(restored))
+	}
+	for i, want := range []uint{10, 11, 99} {
+		if restored[i].CreatorID != want || restored[i].RepositoryID != 2 || restored[i].SHA != trunk {
+			t.Errorf("status %d = %+v, want creator %d", i, restored[i], want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+// Package imports creates repositories from existing history, fetched from
+// a remote URL or read from an uploaded git bundle or project archive, and
+// exports projects as archives.
+package imports
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/mirror"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
+	"gitlab-tool/internal/repository"
+)
+
+// Import states.
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+// Import sources.
+const (
+	SourceURL     = "url"
+	SourceBundle  = "bundle"
+	SourceArchive = "archive"
+)
+
+// importTimeout bounds a single import.
+const importTimeout = 30 * time.Minute
+
+// sizeCheckInterval is how often the size of a repository being imported is
+// checked against the limit.
+const sizeCheckInterval = time.Second
+
+var (
+	// ErrTooLarge is returned when an import exceeds the size limit.
+	ErrTooLarge = errors.New("repository exceeds the import size limit")
+	// ErrStorageLimit is returned when an import would take the repository
+	// or its owner past a storage limit.
+	ErrStorageLimit = errors.New("repository exceeds the storage left to it or its owner")
+)
+
+type Service struct {
+	importRepo      *repository.ImportRepository
+	repoRepo        *repository.RepositoryRepository
+	statusRepo      *repository.CommitStatusRepository
+	userRepo        *repository.UserRepository
+	gitService      *git.Service
+	quotaService    *quota.Service
+	locks           *jobs.Locks
+	maxSize         int64
+	importListeners []func(repo *models.Repository)
+}
+
+func NewService(importRepo *repository.ImportRepository, repoRepo *repository.RepositoryRepository, statusRepo *repository.CommitStatusRepository, userRepo *repository.UserRepository, gitService *git.Service, quotaService *quota.Service, locks *jobs.Locks, maxSize int64) *Service {
+	return &Service{
+		importRepo:   importRepo,
+		repoRepo:     repoRepo,
+		statusRepo:   statusRepo,
+		userRepo:     userRepo,
+		gitService:   gitService,
+		quotaService: quotaService,
+		locks:        locks,
+		maxSize:      maxSize,
+	}
+}
+
+// MaxSize is the largest repository, in bytes, that can be imported.
+func (s *Service) MaxSize() int64 {
+	return s.maxSize
+}
+
+// OnImport registers a listener that runs after a repository has been
+// imported, like a push would.
+func (s *Service) OnImport(listener func(repo *models.Repository)) {
+	s.importListeners = append(s.importListeners, listener)
+}
+
+// Schedule runs an import in the background. For bundle and archive imports
+// uploadPath is the uploaded file, which is removed once the import has
+// finished.
+func (s *Service) Schedule(imp *models.RepositoryImport, uploadPath string) {
+	importID := imp.ID
+	go func() {
+		if uploadPath != "" {
+			defer os.Remove(uploadPath)
+		}
+		current, err := s.importRepo.FindByID(importID)
+		if err == nil {
+			err = s.Run(current, uploadPath)
+		}
+		if err != nil {
+			fmt.Printf("Warning: Failed to run import %d: %v\n", importID, err)
+		}
+	}()
+}
+
+// Run imports into the repository created for imp and records the outcome.
+// A failed import removes the repository, so the name can be used again.
+func (s *Service) Run(imp *models.RepositoryImport, uploadPath string) error {
+	repo, err := s.repoRepo.FindByID(*imp.RepositoryID)
+	if err != nil {
+		return err
+	}
+	repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	// Keeps maintenance away until the import has finished or been cleaned up
+	unlock := s.locks.RLock(repo.ID)
+	defer unlock()
+
+	now := time.Now()
+	imp.Status = StatusRunning
+	imp.StartedAt = &now
+	if err := s.importRepo.Update(imp); err != nil {
+		return err
+	}
+
+	// Imports stop at the import size limit or at the storage left to the
+	// repository and its owner, whichever comes first
+	limit, tooLarge := s.maxSize, ErrTooLarge
+	if remaining, limited, err := s.quotaService.Remaining(repo); err != nil {
+		fmt.Printf("Warning: Failed to check storage quota of repository %d: %v\n", repo.ID, err)
+	} else if limited && remaining < limit {
+		limit, tooLarge = remaining, ErrStorageLimit
+	}
+
+	remote := ""
+	err = s.withSizeLimit(repoPath, limit, tooLarge, func(ctx context.Context) error {
+		switch imp.Source {
+		case SourceBundle:
+			return s.gitService.ImportBundle(ctx, repoPath, uploadPath)
+		case SourceArchive:
+			return s.importArchive(ctx, imp, repo, uploadPath)
+		}
+		var err error
+		remote, err = mirror.AuthURL(imp.URL, imp.Username, imp.Password)
+		if err != nil {
+			return err
+		}
+		return s.gitService.ImportFromURL(ctx, repoPath, remote)
+	})
+	if err != nil {
+		message := mirror.Redact(err.Error(), remote, imp.URL)
+		if cleanupErr := s.fail(imp, repo, message); cleanupErr != nil {
+			return fmt.Errorf("%s (cleanup failed: %w)", message, cleanupErr)
+		}
+		return errors.New(message)
+	}
+
+	finished := time.Now()
+	imp.Status = StatusSuccess
+	imp.FinishedAt = &finished
+	// Credentials are only needed while fetching
+	imp.Username, imp.Password = "", ""
+	if err := s.importRepo.Update(imp); err != nil {
+		return err
+	}
+
+	for _, listener := range s.importListeners {
+		go listener(repo)
+	}
+	return nil
+}
+
+// FailInterrupted fails imports left unfinished by a previous run of the
+// server and removes their repositories.
+func (s *Service) FailInterrupted() {
+	imports, err := s.importRepo.FindUnfinished()
+	if err != nil {
+		fmt.Printf("Warning: Failed to list unfinished imports: %v\n", err)
+		return
+	}
+	for i := range imports {
+		imp := &imports[i]
+		var repo *models.Repository
+		if imp.RepositoryID != nil {
+			repo, _ = s.repoRepo.FindByID(*imp.RepositoryID)
+		}
+		if err := s.fail(imp, repo, "import interrupted by a server restart"); err != nil {
+			fmt.Printf("Warning: Failed to clean up import %d: %v\n", imp.ID, err)
+		}
+	}
+}
+
+// withSizeLimit runs fn with a context that is cancelled with tooLarge when
+// the repository grows beyond limit bytes, or when the import times out.
+func (s *Service) withSizeLimit(repoPath string, limit int64, tooLarge error, fn func(ctx context.Context) error) error {
+	ctx, cancel := context.WithTimeoutCause(context.Background(), importTimeout, errors.New("import timed out"))
+	defer cancel()
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	defer cancelCause(nil)
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		ticker := time.NewTicker(sizeCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				if size, err := s.gitService.RepositorySize(repoPath); err == nil && size > limit {
+					cancelCause(tooLarge)
+					return
+				}
+			}
+		}
+	}()
+
+	err := fn(ctx)
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+	if err != nil {
+		return err
+	}
+	// The last check may have missed the end of the fetch
+	if size, err := s.gitService.RepositorySize(repoPath); err != nil {
+		return err
+	} else if size > limit {
+		return tooLarge
+	}
+	return nil
+}
+
+// fail records a failed import and removes its repository. repo may be nil
+// if the repository is already gone.
+func (s *Service) fail(imp *models.RepositoryImport, repo *models.Repository, message string) error {
+	// Detach the import first so purging the repository keeps the record
+	now := time.Now()
+	imp.RepositoryID = nil
+	imp.Status = StatusFailed
+	imp.Error = message
+	imp.FinishedAt = &now
+	imp.Username, imp.Password = "", ""
+	if err := s.importRepo.Update(imp); err != nil {
+		return err
+	}
+
+	if repo == nil {
+		return nil
+	}
+	for _, path := range []string{
+		s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name),
+		s.gitService.GetWikiPath(repo.Owner.Username, repo.Name),
+	} {
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+	}
+	return s.repoRepo.Purge(repo.ID)
+}
@@ -0,0 +1,297 @@
+// Package integrity checks that the repositories in the database and on disk
+// agree with each other and that their git data is intact.
+package integrity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Kinds of issues.
+const (
+	// KindMissing is a repository row without its bare repository
+	KindMissing = "missing"
+	// KindOrphan is a bare repository or wiki directory without a row
+	KindOrphan = "orphan"
+	// KindCorrupt is a repository that git fsck reports problems in
+	KindCorrupt = "corrupt"
+)
+
+const (
+	ResolutionRepaired    = "repaired"
+	ResolutionQuarantined = "quarantined"
+)
+
+// gracePeriod keeps repositories that are still being created or moved from
+// being reported while the database and disk briefly disagree.
+const gracePeriod = 10 * time.Minute
+
+// fsckTimeout bounds the check of a single repository.
+const fsckTimeout = 30 * time.Minute
+
+var (
+	// ErrNotApplicable is returned for fixes that do not apply to an issue.
+	ErrNotApplicable = errors.New("this fix does not apply to the issue")
+	// ErrResolved is returned for issues that have already been dealt with.
+	ErrResolved = errors.New("issue has already been resolved")
+	// ErrStale is returned for issues that no longer hold on a second look.
+	ErrStale = errors.New("issue no longer applies; run a new check")
+	// ErrBusy is returned for fixes to a repository that is being written to.
+	ErrBusy = errors.New("repository is being written to; try again later")
+)
+
+// Status describes the last check.
+type Status struct {
+	Running   bool       `json:"running"`
+	CheckedAt *time.Time `json:"checked_at"`
+}
+
+type Service struct {
+	repoRepo      *repository.RepositoryRepository
+	importRepo    *repository.ImportRepository
+	integrityRepo *repository.IntegrityRepository
+	gitService    *git.Service
+	locks         *jobs.Locks
+	checks        *jobs.Coalescer
+
+	mu     sync.Mutex
+	status Status
+}
+
+// NewService creates the integrity service. locks are the per-repository
+// locks shared with everything that writes to repositories.
+func NewService(repoRepo *repository.RepositoryRepository, importRepo *repository.ImportRepository, integrityRepo *repository.IntegrityRepository, gitService *git.Service, locks *jobs.Locks) *Service {
+	return &Service{
+		repoRepo:      repoRepo,
+		importRepo:    importRepo,
+		integrityRepo: integrityRepo,
+		gitService:    gitService,
+		locks:         locks,
+		checks:        jobs.NewCoalescer(),
+	}
+}
+
+// Start checks every repository once per interval, the first time after one
+// interval has passed, until the process exits.
+func (s *Service) Start(interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			s.Trigger()
+		}
+	}()
+}
+
+// Trigger starts a check in the background. A check requested while one is
+// running starts again once it has finished.
+func (s *Service) Trigger() {
+	s.checks.Run(0, func() {
+		if err := s.Check(); err != nil {
+			fmt.Printf("Warning: Integrity check failed: %v\n", err)
+		}
+	})
+}
+
+// Status reports whether a check is running and when the last one finished.
+func (s *Service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// Check compares the repository rows with the directories on disk, runs git
+// fsck on every repository and wiki, and replaces the stored findings.
+func (s *Service) Check() error {
+	s.setRunning(true, nil)
+	defer s.setRunning(false, nil)
+
+	repos, err := s.repoRepo.FindAll()
+	if err != nil {
+		return err
+	}
+	dirs, err := s.gitService.ListRepositoryDirs()
+	if err != nil {
+		return err
+	}
+
+	onDisk := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		onDisk[dir.Path] = true
+	}
+	known := make(map[string]bool, len(repos))
+
+	var issues []models.IntegrityIssue
+	cutoff := time.Now().Add(-gracePeriod)
+	for i := range repos {
+		repo := &repos[i]
+		repoDir, wikiDir := repositoryDirs(repo)
+		known[repoDir] = true
+		known[wikiDir] = true
+
+		if repo.CreatedAt.After(cutoff) || s.importRepo.IsImporting(repo.ID) {
+			continue
+		}
+		if !s.gitService.RepositoryExists(repo.Owner.Username, repo.Name) {
+			issues = append(issues, models.IntegrityIssue{
+				Kind: KindMissing, RepositoryID: &repo.ID, Path: repoDir,
+				Details: "the repository has a database row but no bare repository on disk",
+			})
+			continue
+		}
+
+		checks := [][2]string{{repoDir, s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)}}
+		if onDisk[wikiDir] {
+			checks = append(checks, [2]string{wikiDir, s.gitService.GetWikiPath(repo.Owner.Username, repo.Name)})
+		}
+		for _, check := range checks {
+			if err := s.fsck(check[1]); err != nil {
+				issues = append(issues, models.IntegrityIssue{
+					Kind: KindCorrupt, RepositoryID: &repo.ID, Path: check[0], Details: err.Error(),
+				})
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		if known[dir.Path] || dir.ModTime.After(cutoff) {
+			continue
+		}
+		issues = append(issues, models.IntegrityIssue{
+			Kind: KindOrphan, Path: dir.Path,
+			Details: "the directory does not belong to any repository",
+		})
+	}
+
+	if err := s.integrityRepo.Replace(issues); err != nil {
+		return err
+	}
+	now := time.Now()
+	s.setRunning(false, &now)
+	return nil
+}
+
+// repositoryDirs returns the directories of a repository and its wiki,
+// relative to the repositories root.
+func repositoryDirs(repo *models.Repository) (string, string) {
+	return repo.Owner.Username + "/" + repo.Name + ".git", repo.Owner.Username + "/" + repo.Name + ".wiki.git"
+}
+
+func (s *Service) fsck(repoPath string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), fsckTimeout)
+	defer cancel()
+	return s.gitService.Fsck(ctx, repoPath)
+}
+
+func (s *Service) setRunning(running bool, checkedAt *time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status.Running = running
+	if checkedAt != nil {
+		s.status.CheckedAt = checkedAt
+	}
+}
+
+// Repair fixes a missing repository by initializing an empty one in its
+// place, so the repository can be pushed to again. Its history is lost
+// unless it is restored from a backup.
+func (s *Service) Repair(issue *models.IntegrityIssue) error {
+	if issue.Resolution != "" {
+		return ErrResolved
+	}
+	if issue.Kind != KindMissing || issue.RepositoryID == nil {
+		return ErrNotApplicable
+	}
+
+	repo, err := s.repoRepo.FindByID(*issue.RepositoryID)
+	if err != nil {
+		return err
+	}
+	if !s.gitService.RepositoryExists(repo.Owner.Username, repo.Name) {
+		if err := s.gitService.InitBareRepository(repo.Owner.Username, repo.Name); err != nil {
+			return err
+		}
+	}
+	return s.integrityRepo.Resolve(issue, ResolutionRepaired, "initialized an empty repository")
+}
+
+// Quarantine moves an orphaned or corrupt directory aside so it no longer
+// serves requests, keeping it for manual recovery. A quarantined corrupt
+// repository is reported as missing by the next check.
+//
+// The issue may be out of date, so it is checked again first: an orphan must
+// still have no repository, and a corrupt repository must still be where it
+// was and still fail git fsck. A corrupt repository is checked and moved
+// under its exclusive lock, so no write is in progress while it is.
+func (s *Service) Quarantine(issue *models.IntegrityIssue) error {
+	if issue.Resolution != "" {
+		return ErrResolved
+	}
+
+	switch issue.Kind {
+	case KindOrphan:
+		// Nothing writes to a directory without a repository, so there is
+		// nothing to lock
+		owner, dir, _ := strings.Cut(issue.Path, "/")
+		name := strings.TrimSuffix(strings.TrimSuffix(dir, ".git"), ".wiki")
+		_, err := s.repoRepo.FindByUsernameAndName(owner, name)
+		if err == nil {
+			return ErrStale
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+	case KindCorrupt:
+		if issue.RepositoryID == nil {
+			return ErrNotApplicable
+		}
+		repo, err := s.repoRepo.FindByID(*issue.RepositoryID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrStale
+		}
+		if err != nil {
+			return err
+		}
+		repoDir, wikiDir := repositoryDirs(repo)
+		repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+		switch issue.Path {
+		case repoDir:
+		case wikiDir:
+			repoPath = s.gitService.GetWikiPath(repo.Owner.Username, repo.Name)
+		default:
+			// Renamed or transferred since the check
+			return ErrStale
+		}
+
+		unlock, ok := s.locks.TryLock(repo.ID)
+		if !ok {
+			return ErrBusy
+		}
+		defer unlock()
+		if err := s.fsck(repoPath); err == nil {
+			return ErrStale
+		}
+	default:
+		return ErrNotApplicable
+	}
+
+	moved, err := s.gitService.Quarantine(issue.Path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrStale
+	}
+	if err != nil {
+		return err
+	}
+	return s.integrityRepo.Resolve(issue, ResolutionQuarantined, "moved to "+moved)
+}
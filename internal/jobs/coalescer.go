@@ -0,0 +1,64 @@
+// Package jobs contains helpers for background work.
+package jobs
+
+import "sync"
+
+// Coalescer runs at most one job per key at a time. Requests that arrive while
+// a job for the same key is running are folded into a single rerun, so a burst
+// of pushes to one repository costs at most two runs.
+type Coalescer struct {
+	mu      sync.Mutex
+	running map[uint]bool
+	pending map[uint]bool
+}
+
+func NewCoalescer() *Coalescer {
+	return &Coalescer{
+		running: make(map[uint]bool),
+		pending: make(map[uint]bool),
+	}
+}
+
+// Run starts job in the background unless one is already running for key.
+func (c *Coalescer) Run(key uint, job func()) {
+	if c.start(key) {
+		go c.loop(key, job)
+	}
+}
+
+// Do is Run in the calling goroutine. It returns once job and any reruns
+// requested meanwhile have finished, or right away if a job is already
+// running for key, which then runs once more in its place.
+func (c *Coalescer) Do(key uint, job func()) {
+	if c.start(key) {
+		c.loop(key, job)
+	}
+}
+
+// start marks key as running, or as pending if it already is, and reports
+// whether the caller should run the job.
+func (c *Coalescer) start(key uint) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.running[key] {
+		c.pending[key] = true
+		return false
+	}
+	c.running[key] = true
+	return true
+}
+
+func (c *Coalescer) loop(key uint, job func()) {
+	for {
+		job()
+
+		c.mu.Lock()
+		if !c.pending[key] {
+			delete(c.running, key)
+			c.mu.Unlock()
+			return
+		}
+		delete(c.pending, key)
+		c.mu.Unlock()
+	}
+}
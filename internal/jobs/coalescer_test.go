@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCoalescerDo(t *testing.T) {
+	coalescer := NewCoalescer()
+
+	runs := 0
+	coalescer.Do(1, func() { runs++ })
+	if runs != 1 {
+		t.Fatalf("Do ran the job %d times, want 1", runs)
+	}
+
+	// A Do for a key whose job is running returns at once and leaves a
+	// single rerun to the running job
+	started, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	background := 0
+	done := make(chan struct{})
+	go coalescer.Do(2, func() {
+		mu.Lock()
+		background++
+		first := background == 1
+		mu.Unlock()
+		if first {
+			close(started)
+			<-release
+		} else {
+			close(done)
+		}
+	})
+	<-started
+	coalescer.Do(2, func() { t.Error("Do ran its job while another was running") })
+	coalescer.Do(2, func() { t.Error("Do ran its job while another was running") })
+	close(release)
+	<-done
+
+	mu.Lock()
+	defer mu.Unlock()
+	if background != 2 {
+		t.Errorf("running job ran %d times, want 2", background)
+	}
+}
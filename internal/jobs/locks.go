@@ -0,0 +1,51 @@
+package jobs
+
+import "sync"
+
+// Locks is a set of read-write locks, one per key. Keys are never forgotten,
+// which is fine for the number of repositories a server holds.
+type Locks struct {
+	mu    sync.Mutex
+	locks map[uint]*sync.RWMutex
+}
+
+func NewLocks() *Locks {
+	return &Locks{locks: make(map[uint]*sync.RWMutex)}
+}
+
+func (l *Locks) get(key uint) *sync.RWMutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &sync.RWMutex{}
+		l.locks[key] = lock
+	}
+	return lock
+}
+
+// RLock takes the shared lock of key, which any number of holders can share,
+// and returns the function that releases it.
+func (l *Locks) RLock(key uint) func() {
+	lock := l.get(key)
+	lock.RLock()
+	return lock.RUnlock
+}
+
+// Lock takes the exclusive lock of key and returns the function that
+// releases it.
+func (l *Locks) Lock(key uint) func() {
+	lock := l.get(key)
+	lock.Lock()
+	return lock.Unlock
+}
+
+// TryLock takes the exclusive lock of key unless it is held in either mode,
+// and returns the function that releases it and whether it was taken.
+func (l *Locks) TryLock(key uint) (func(), bool) {
+	lock := l.get(key)
+	if !lock.TryLock() {
+		return nil, false
+	}
+	return lock.Unlock, true
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import "testing"
+
+func TestLocksTryLock(t *testing.T) {
+	locks := NewLocks()
+
+	runlock := locks.RLock(1)
+	if _, ok := locks.TryLock(1); ok {
+		t.Fatal("TryLock succeeded while the shared lock was held")
+	}
+	// A failed TryLock must not hold up readers the way a waiting Lock does
+	locks.RLock(1)()
+	runlock()
+
+	unlock, ok := locks.TryLock(1)
+	if !ok {
+		t.Fatal("TryLock failed on a free lock")
+	}
+	if _, ok := locks.TryLock(1); ok {
+		t.Error("TryLock succeeded twice")
+	}
+	if _, ok := locks.TryLock(2); !ok {
+		t.Error("TryLock of another key failed")
+	}
+	unlock()
+}
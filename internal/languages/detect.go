@@ -0,0 +1,167 @@
+// Package languages classifies repository files by programming language and
+// keeps per-repository byte counts up to date.
+package languages
+
+import (
+	"bytes"
+	"path"
+	"strings"
+)
+
+var extensions = map[string]string{
+	".go":     "Go",
+	".js":     "JavaScript",
+	".mjs":    "JavaScript",
+	".cjs":    "JavaScript",
+	".jsx":    "JavaScript",
+	".ts":     "TypeScript",
+	".tsx":    "TypeScript",
+	".vue":    "Vue",
+	".py":     "Python",
+	".rb":     "Ruby",
+	".java":   "Java",
+	".kt":     "Kotlin",
+	".kts":    "Kotlin",
+	".scala":  "Scala",
+	".groovy": "Groovy",
+	".c":      "C",
+	".h":      "C",
+	".cc":     "C++",
+	".cpp":    "C++",
+	".cxx":    "C++",
+	".hpp":    "C++",
+	".hh":     "C++",
+	".cs":     "C#",
+	".m":      "Objective-C",
+	".swift":  "Swift",
+	".rs":     "Rust",
+	".php":    "PHP",
+	".pl":     "Perl",
+	".pm":     "Perl",
+	".lua":    "Lua",
+	".r":      "R",
+	".hs":     "Haskell",
+	".ex":     "Elixir",
+	".exs":    "Elixir",
+	".erl":    "Erlang",
+	".clj":    "Clojure",
+	".dart":   "Dart",
+	".sh":     "Shell",
+	".bash":   "Shell",
+	".zsh":    "Shell",
+	".ps1":    "PowerShell",
+	".sql":    "SQL",
+	".html":   "HTML",
+	".htm":    "HTML",
+	".css":    "CSS",
+	".scss":   "SCSS",
+	".sass":   "Sass",
+	".less":   "Less",
+	".proto":  "Protocol Buffer",
+	".tf":     "HCL",
+	".gd":     "GDScript",
+	".glsl":   "GLSL",
+	".hlsl":   "HLSL",
+}
+
+var filenames = map[string]string{
+	"makefile":       "Makefile",
+	"gnumakefile":    "Makefile",
+	"dockerfile":     "Dockerfile",
+	"cmakelists.txt": "CMake",
+	"rakefile":       "Ruby",
+	"gemfile":        "Ruby",
+	"jenkinsfile":    "Groovy",
+}
+
+var interpreters = map[string]string{
+	"sh":      "Shell",
+	"bash":    "Shell",
+	"zsh":     "Shell",
+	"python":  "Python",
+	"python2": "Python",
+	"python3": "Python",
+	"node":    "JavaScript",
+	"ruby":    "Ruby",
+	"perl":    "Perl",
+	"php":     "PHP",
+	"lua":     "Lua",
+}
+
+// vendoredDirs are path segments whose contents are third-party code.
+var vendoredDirs = map[string]bool{
+	"vendor":           true,
+	"node_modules":     true,
+	"third_party":      true,
+	"bower_components": true,
+}
+
+// generatedSuffixes mark files produced by tools rather than written by hand.
+var generatedSuffixes = []string{
+	".pb.go",
+	".pb.gw.go",
+	"_generated.go",
+	".gen.go",
+	".min.js",
+	".min.css",
+	"_pb2.py",
+}
+
+// IsExcluded reports whether a path is vendored or generated and should not
+// count towards language statistics.
+func IsExcluded(filePath string) bool {
+	segments := strings.Split(filePath, "/")
+	for _, segment := range segments[:len(segments)-1] {
+		if vendoredDirs[segment] {
+			return true
+		}
+	}
+
+	name := strings.ToLower(segments[len(segments)-1])
+	for _, suffix := range generatedSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// DetectByName classifies a file from its name alone, returning "" if the
+// name is not conclusive.
+func DetectByName(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	if language, ok := filenames[name]; ok {
+		return language
+	}
+	return extensions[path.Ext(name)]
+}
+
+// DetectByShebang classifies a script from its "#!" line, handling both
+// "#!/bin/bash" and "#!/usr/bin/env python3" forms.
+func DetectByShebang(content []byte) string {
+	if !bytes.HasPrefix(content, []byte("#!")) {
+		return ""
+	}
+
+	line, _, _ := bytes.Cut(content[2:], []byte("\n"))
+	fields := strings.Fields(string(line))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		// Skip env options such as -S
+		for _, field := range fields[1:] {
+			if !strings.HasPrefix(field, "-") {
+				interpreter = field
+				break
+			}
+		}
+	}
+	if language, ok := interpreters[interpreter]; ok {
+		return language
+	}
+	// Versioned interpreters such as python3.12
+	return interpreters[strings.TrimRight(interpreter, "0123456789.")]
+}
@@ -0,0 +1,93 @@
+package languages
+
+import (
+	"testing"
+
+	"gitlab-tool/internal/git"
+)
+
+func TestDetectByName(t *testing.T) {
+	tests := map[string]string{
+		"main.go":            "Go",
+		"web/App.TSX":        "TypeScript",
+		"Makefile":           "Makefile",
+		"docker/Dockerfile":  "Dockerfile",
+		"src/CMakeLists.txt": "CMake",
+		"README.md":          "",
+		"bin/deploy":         "",
+	}
+	for filePath, want := range tests {
+		if got := DetectByName(filePath); got != want {
+			t.Errorf("DetectByName(%q) = %q, want %q", filePath, got, want)
+		}
+	}
+}
+
+func TestDetectByShebang(t *testing.T) {
+	tests := map[string]string{
+		"#!/bin/bash\necho hi":             "Shell",
+		"#!/usr/bin/env python3\nprint()":  "Python",
+		"#!/usr/bin/env -S node --flag\n":  "JavaScript",
+		"#!/usr/local/bin/python3.12\n":    "Python",
+		"echo no shebang\n":                "",
+		"#!/usr/bin/unknown-interpreter\n": "",
+	}
+	for content, want := range tests {
+		if got := DetectByShebang([]byte(content)); got != want {
+			t.Errorf("DetectByShebang(%q) = %q, want %q", content, got, want)
+		}
+	}
+}
+
+func TestIsExcluded(t *testing.T) {
+	excluded := []string{"vendor/github.com/x/y.go", "web/node_modules/react/index.js", "api/service.pb.go", "static/app.min.js"}
+	for _, filePath := range excluded {
+		if !IsExcluded(filePath) {
+			t.Errorf("expected %q to be excluded", filePath)
+		}
+	}
+
+	included := []string{"main.go", "internal/vendor.go", "cmd/vendorctl/main.go"}
+	for _, filePath := range included {
+		if IsExcluded(filePath) {
+			t.Errorf("expected %q to be included", filePath)
+		}
+	}
+}
+
+func TestCompute(t *testing.T) {
+	gitService := git.NewService(t.TempDir())
+	if err := gitService.InitBareRepository("alice", "project"); err != nil {
+		t.Fatalf("InitBareRepository failed: %v", err)
+	}
+	service := NewService(nil, gitService)
+
+	stats, err := service.Compute("alice", "project")
+	if err != nil || len(stats) != 0 {
+		t.Fatalf("expected no languages for an empty repository, got %v (%v)", stats, err)
+	}
+
+	_, err = gitService.CommitFiles(gitService.GetRepositoryPath("alice", "project"), git.CommitOptions{
+		Branch:  "main",
+		Message: "Add code",
+		Author:  git.Signature{Name: "alice", Email: "alice@example.com"},
+		Files: []git.FileChange{
+			{Path: "main.go", Content: []byte("package main\n")},
+			{Path: "api/api.pb.go", Content: []byte("package api // generated\n")},
+			{Path: "vendor/lib/lib.go", Content: []byte("package lib\n")},
+			{Path: "scripts/release", Content: []byte("#!/bin/sh\nexit 0\n")},
+			{Path: "README.md", Content: []byte("# Project\n")},
+		},
+	})
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+
+	stats, err = service.Compute("alice", "project")
+	if err != nil {
+		t.Fatalf("Compute failed: %v", err)
+	}
+	if len(stats) != 2 || stats["Go"] != 13 || stats["Shell"] != 17 {
+		t.Errorf("unexpected stats %v", stats)
+	}
+}
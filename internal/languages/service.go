@@ -0,0 +1,84 @@
+package languages
+
+import (
+	"fmt"
+	"path"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+)
+
+type Service struct {
+	langRepo   *repository.LanguageRepository
+	gitService *git.Service
+	refreshes  *jobs.Coalescer
+}
+
+func NewService(langRepo *repository.LanguageRepository, gitService *git.Service) *Service {
+	return &Service{
+		langRepo:   langRepo,
+		gitService: gitService,
+		refreshes:  jobs.NewCoalescer(),
+	}
+}
+
+// Compute walks the tree of the default branch and returns the number of
+// bytes per language. Empty repositories have no languages.
+func (s *Service) Compute(username, repoName string) (map[string]int64, error) {
+	repoPath := s.gitService.GetRepositoryPath(username, repoName)
+	stats := make(map[string]int64)
+
+	branch, err := s.gitService.DefaultBranch(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.gitService.ResolveCommit(username, repoName, branch); err != nil {
+		return stats, nil
+	}
+
+	entries, err := s.gitService.ListTree(repoPath, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		// Skip symlinks and submodules
+		if entry.Type != "blob" || entry.Mode == "120000" || IsExcluded(entry.Path) {
+			continue
+		}
+
+		language := DetectByName(entry.Path)
+		if language == "" && path.Ext(entry.Path) == "" {
+			content, err := s.gitService.ReadFile(repoPath, branch, entry.Path)
+			if err != nil {
+				return nil, err
+			}
+			language = DetectByShebang(content)
+		}
+		if language != "" {
+			stats[language] += entry.Size
+		}
+	}
+	return stats, nil
+}
+
+// Refresh recomputes and stores the language breakdown of a repository.
+func (s *Service) Refresh(repo *models.Repository) error {
+	stats, err := s.Compute(repo.Owner.Username, repo.Name)
+	if err != nil {
+		return fmt.Errorf("failed to compute languages: %w", err)
+	}
+	return s.langRepo.Replace(repo.ID, stats)
+}
+
+// Schedule refreshes a repository in the background. Pushes that arrive while
+// a refresh is running are coalesced into a single follow-up run.
+func (s *Service) Schedule(repo *models.Repository) {
+	s.refreshes.Run(repo.ID, func() {
+		if err := s.Refresh(repo); err != nil {
+			fmt.Printf("Warning: Failed to refresh languages for repository %d: %v\n", repo.ID, err)
+		}
+	})
+}
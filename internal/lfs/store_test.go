@@ -0,0 +1,70 @@
+package lfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore(t *testing.T) {
+	root := t.TempDir()
+	store := NewStore(root)
+	content := "large asset"
+	sum := sha256.Sum256([]byte(content))
+	oid := hex.EncodeToString(sum[:])
+
+	if _, err := store.Size(oid); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Size before upload = %v, want ErrObjectNotFound", err)
+	}
+
+	size, err := store.Put(oid, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	if size != int64(len(content)) {
+		t.Errorf("Put size = %d, want %d", size, len(content))
+	}
+	if _, err := os.Stat(filepath.Join(root, oid[0:2], oid[2:4], oid)); err != nil {
+		t.Errorf("object not stored at its sharded path: %v", err)
+	}
+
+	file, err := store.Open(oid)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer file.Close()
+	if data, _ := io.ReadAll(file); string(data) != content {
+		t.Errorf("content = %q, want %q", data, content)
+	}
+
+	// Content that does not hash to the ID is rejected and not kept
+	other := strings.Repeat("0", 64)
+	if _, err := store.Put(other, strings.NewReader(content)); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("Put with wrong ID = %v, want ErrHashMismatch", err)
+	}
+	if _, err := store.Size(other); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("rejected object was stored")
+	}
+	if entries, _ := os.ReadDir(filepath.Join(root, "tmp")); len(entries) != 0 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestValidOID(t *testing.T) {
+	for oid, want := range map[string]bool{
+		strings.Repeat("a", 64):         true,
+		strings.Repeat("A", 64):         false,
+		strings.Repeat("a", 63):         false,
+		"../" + strings.Repeat("a", 61): false,
+		strings.Repeat("a", 64) + "\n":  false,
+	} {
+		if got := ValidOID(oid); got != want {
+			t.Errorf("ValidOID(%q) = %v, want %v", oid, got, want)
+		}
+	}
+}
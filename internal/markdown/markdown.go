@@ -0,0 +1,253 @@
+// Package markdown renders GitHub-flavoured markdown to sanitized HTML,
+// resolving repository-relative links and linkifying references such as
+// #123, !45, @user and commit SHAs.
+package markdown
+
+import (
+	"bytes"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// Context describes where a document lives so relative links and references
+// can be resolved. A nil Context renders without any repository context.
+type Context struct {
+	Owner string
+	Repo  string
+	Ref   string
+	// Path is the directory of the document inside the repository.
+	Path string
+	// Wiki resolves relative links against the repository wiki instead.
+	Wiki bool
+}
+
+var (
+	contextKey = parser.NewContextKey()
+
+	renderer = goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithParserOptions(
+			parser.WithASTTransformers(util.Prioritized(&linkTransformer{}, 100)),
+		),
+		// Raw HTML is passed through and then cleaned by the sanitizer
+		goldmark.WithRendererOptions(html.WithUnsafe()),
+	)
+
+	policy = newPolicy()
+
+	// A reference must not directly follow a word character or one of the
+	// listed symbols, so emails, URLs and HTML entities are left alone.
+	referencePattern = regexp.MustCompile(`(?:^|[^\w/.@#!&])([#!]\d+|@[A-Za-z0-9][A-Za-z0-9_-]*|[0-9a-f]{7,40})`)
+)
+
+func newPolicy() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+#-]+$`)).OnElements("code")
+	p.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	p.AllowAttrs("checked", "disabled").OnElements("input")
+	return p
+}
+
+// Render converts markdown source to sanitized HTML.
+func Render(source []byte, ctx *Context) (string, error) {
+	pc := parser.NewContext()
+	if ctx != nil {
+		pc.Set(contextKey, ctx)
+	}
+
+	var buf bytes.Buffer
+	if err := renderer.Convert(source, &buf, parser.WithContext(pc)); err != nil {
+		return "", err
+	}
+
+	return policy.Sanitize(buf.String()), nil
+}
+
+// linkTransformer rewrites relative link and image destinations and turns
+// textual references into links.
+type linkTransformer struct{}
+
+func (t *linkTransformer) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	ctx, _ := pc.Get(contextKey).(*Context)
+	source := reader.Source()
+
+	var textParents []ast.Node
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+
+		switch node := n.(type) {
+		case *ast.Link:
+			if ctx != nil {
+				node.Destination = []byte(ctx.resolve(string(node.Destination), false))
+			}
+			// Never linkify inside an existing link
+			return ast.WalkSkipChildren, nil
+		case *ast.Image:
+			if ctx != nil {
+				node.Destination = []byte(ctx.resolve(string(node.Destination), true))
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.AutoLink, *ast.CodeSpan, *ast.CodeBlock, *ast.FencedCodeBlock, *ast.HTMLBlock, *ast.RawHTML:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			if parent := node.Parent(); len(textParents) == 0 || textParents[len(textParents)-1] != parent {
+				textParents = append(textParents, parent)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// Mutate after walking so the walk never sees half-rewritten siblings
+	for _, parent := range textParents {
+		mergeTexts(parent)
+		linkifyReferences(parent, source, ctx)
+	}
+}
+
+// mergeTexts joins adjacent text nodes that cover contiguous source, because
+// the parser splits text at characters such as '!' that could start markup.
+func mergeTexts(parent ast.Node) {
+	for child := parent.FirstChild(); child != nil; {
+		next := child.NextSibling()
+		current, ok := child.(*ast.Text)
+		following, nextOK := next.(*ast.Text)
+		if ok && nextOK && !current.IsRaw() && !following.IsRaw() &&
+			!current.SoftLineBreak() && !current.HardLineBreak() &&
+			current.Segment.Stop == following.Segment.Start {
+			current.Segment = current.Segment.WithStop(following.Segment.Stop)
+			current.SetSoftLineBreak(following.SoftLineBreak())
+			current.SetHardLineBreak(following.HardLineBreak())
+			parent.RemoveChild(parent, following)
+			continue
+		}
+		child = next
+	}
+}
+
+func linkifyReferences(parent ast.Node, source []byte, ctx *Context) {
+	for child := parent.FirstChild(); child != nil; {
+		next := child.NextSibling()
+		node, ok := child.(*ast.Text)
+		if !ok || node.IsRaw() {
+			child = next
+			continue
+		}
+
+		segment := node.Segment
+		value := segment.Value(source)
+		start := 0
+		for _, match := range referencePattern.FindAllSubmatchIndex(value, -1) {
+			refStart, refStop := match[2], match[3]
+			if refStop < len(value) && isWordByte(value[refStop]) {
+				continue
+			}
+
+			destination := ctx.referenceURL(string(value[refStart:refStop]))
+			if destination == "" {
+				continue
+			}
+
+			if refStart > start {
+				parent.InsertBefore(parent, node, ast.NewTextSegment(text.NewSegment(segment.Start+start, segment.Start+refStart)))
+			}
+			link := ast.NewLink()
+			link.Destination = []byte(destination)
+			link.AppendChild(link, ast.NewTextSegment(text.NewSegment(segment.Start+refStart, segment.Start+refStop)))
+			parent.InsertBefore(parent, node, link)
+			start = refStop
+		}
+
+		if start > 0 {
+			node.Segment = text.NewSegment(segment.Start+start, segment.Stop)
+			if node.Segment.Len() == 0 && !node.SoftLineBreak() && !node.HardLineBreak() {
+				parent.RemoveChild(parent, node)
+			}
+		}
+		child = next
+	}
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || ('0' <= b && b <= '9') || ('a' <= b && b <= 'z') || ('A' <= b && b <= 'Z')
+}
+
+// referenceURL returns the link target for a textual reference, or "" if it
+// should stay plain text.
+func (ctx *Context) referenceURL(reference string) string {
+	if strings.HasPrefix(reference, "@") {
+		return "/" + url.PathEscape(reference[1:])
+	}
+
+	// Issue, merge request and commit references need a repository
+	if ctx == nil || ctx.Owner == "" || ctx.Repo == "" {
+		return ""
+	}
+	base := "/" + url.PathEscape(ctx.Owner) + "/" + url.PathEscape(ctx.Repo)
+
+	switch reference[0] {
+	case '#':
+		return base + "/issues/" + reference[1:]
+	case '!':
+		return base + "/merge_requests/" + reference[1:]
+	}
+
+	// Plain numbers are far more likely than all-digit SHAs
+	if strings.Trim(reference, "0123456789") == "" {
+		return ""
+	}
+	return base + "/commit/" + reference
+}
+
+// resolve maps a relative destination onto the repository. Links point at
+// the blob view and images at the raw file; absolute URLs and anchors are
+// returned unchanged.
+func (ctx *Context) resolve(destination string, image bool) string {
+	if destination == "" || strings.HasPrefix(destination, "#") || strings.HasPrefix(destination, "//") {
+		return destination
+	}
+	u, err := url.Parse(destination)
+	if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" || ctx.Owner == "" || ctx.Repo == "" {
+		return destination
+	}
+
+	// A leading slash is relative to the repository root, like on GitHub
+	target := u.Path
+	if !strings.HasPrefix(target, "/") {
+		target = path.Join("/", ctx.Path, target)
+	}
+	target = strings.TrimPrefix(path.Clean(target), "/")
+
+	var resolved string
+	switch {
+	case ctx.Wiki && image:
+		resolved = path.Join("/", ctx.Owner, ctx.Repo, "wiki", "raw", target)
+	case ctx.Wiki:
+		resolved = path.Join("/", ctx.Owner, ctx.Repo, "wiki", strings.TrimSuffix(target, ".md"))
+	case image:
+		resolved = path.Join("/", ctx.Owner, ctx.Repo, "raw", ctx.ref(), target)
+	default:
+		resolved = path.Join("/", ctx.Owner, ctx.Repo, "blob", ctx.ref(), target)
+	}
+
+	return (&url.URL{Path: resolved, RawQuery: u.RawQuery, Fragment: u.Fragment}).String()
+}
+
+func (ctx *Context) ref() string {
+	if ctx.Ref == "" {
+		return "HEAD"
+	}
+	return ctx.Ref
+}
@@ -0,0 +1,90 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func render(t *testing.T, source string, ctx *Context) string {
+	t.Helper()
+	html, err := Render([]byte(source), ctx)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	return html
+}
+
+func assertContains(t *testing.T, html string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(html, w) {
+			t.Errorf("expected %q in:\n%s", w, html)
+		}
+	}
+}
+
+func TestRenderGFM(t *testing.T) {
+	source := "| a | b |\n|---|---|\n| 1 | 2 |\n\n- [x] done\n- [ ] todo\n\n```go\nfmt.Println()\n```\n\nsee https://example.com\n"
+	html := render(t, source, nil)
+
+	assertContains(t, html,
+		"<table>", "<td>1</td>",
+		`<input checked="" disabled="" type="checkbox"`,
+		`<code class="language-go">`,
+		`<a href="https://example.com" rel="nofollow">https://example.com</a>`,
+	)
+}
+
+func TestRenderSanitizesHTML(t *testing.T) {
+	html := render(t, "<script>alert(1)</script>\n\n<a href=\"javascript:alert(1)\" onclick=\"x()\">hi</a>\n", nil)
+
+	for _, bad := range []string{"<script", "javascript:", "onclick"} {
+		if strings.Contains(html, bad) {
+			t.Errorf("unsanitized %q in:\n%s", bad, html)
+		}
+	}
+}
+
+func TestRenderResolvesRelativeLinks(t *testing.T) {
+	ctx := &Context{Owner: "alice", Repo: "app", Ref: "main", Path: "docs"}
+	html := render(t, "[guide](guide.md#setup) [root](/LICENSE) [up](../README.md) [ext](https://go.dev) [top](#intro)\n\n![logo](img/logo.png)\n", ctx)
+
+	assertContains(t, html,
+		`href="/alice/app/blob/main/docs/guide.md#setup"`,
+		`href="/alice/app/blob/main/LICENSE"`,
+		`href="/alice/app/blob/main/README.md"`,
+		`href="https://go.dev"`,
+		`href="#intro"`,
+		`src="/alice/app/raw/main/docs/img/logo.png"`,
+	)
+}
+
+func TestRenderWikiLinks(t *testing.T) {
+	ctx := &Context{Owner: "alice", Repo: "app", Wiki: true}
+	html := render(t, "[setup](Setup.md)\n", ctx)
+
+	assertContains(t, html, `href="/alice/app/wiki/Setup"`)
+}
+
+func TestRenderLinkifiesReferences(t *testing.T) {
+	ctx := &Context{Owner: "alice", Repo: "app", Ref: "main"}
+	html := render(t, "Fixes #12 and !3, thanks @bob! See deadbeef1 but not 1234567 or bob@example.com.\n\n`#99` stays code.\n", ctx)
+
+	assertContains(t, html,
+		`<a href="/alice/app/issues/12" rel="nofollow">#12</a>`,
+		`<a href="/alice/app/merge_requests/3" rel="nofollow">!3</a>`,
+		`<a href="/bob" rel="nofollow">@bob</a>!`,
+		`<a href="/alice/app/commit/deadbeef1" rel="nofollow">deadbeef1</a>`,
+		"not 1234567 or",
+		"<code>#99</code>",
+	)
+	if strings.Contains(html, `href="/example.com"`) {
+		t.Errorf("email should not be linkified as a user:\n%s", html)
+	}
+}
+
+func TestRenderWithoutRepositoryContext(t *testing.T) {
+	html := render(t, "ping @bob about #12\n", nil)
+
+	assertContains(t, html, `<a href="/bob" rel="nofollow">@bob</a>`, "about #12")
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"gitlab-tool/internal/auth"
+	"gitlab-tool/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GitRealm is sent in the WWW-Authenticate challenge of git endpoints.
+const GitRealm = `Basic realm="gitlab-tool"`
+
+// GitAuthMiddleware authenticates git and Git LFS clients, which send HTTP
+// Basic credentials: a username with either the account password or an API
+// token as the password. Requests without credentials continue anonymously
+// so public repositories can be cloned; handlers challenge them when access
+// needs a user.
+func GitAuthMiddleware(userRepo *repository.UserRepository, jwtSecret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok {
+			c.Next()
+			return
+		}
+
+		if claims, err := auth.ValidateToken(password, jwtSecret); err == nil {
+			c.Set("user_id", claims.UserID)
+			c.Set("username", claims.Username)
+			c.Set("role", claims.Role)
+			c.Next()
+			return
+		}
+
+		user, err := userRepo.FindByUsername(username)
+		if err != nil || !auth.CheckPassword(password, user.Password) {
+			c.Header("WWW-Authenticate", GitRealm)
+			c.Data(http.StatusUnauthorized, "text/plain; charset=utf-8", []byte("Invalid username or password\n"))
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("username", user.Username)
+		c.Set("role", user.Role)
+		c.Next()
+	}
+}
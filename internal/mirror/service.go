@@ -0,0 +1,296 @@
+// Package mirror keeps mirror repositories in sync with their remotes.
+package mirror
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
+	"gitlab-tool/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Sync states recorded on a mirror.
+const (
+	StatusPending = "pending"
+	StatusSuccess = "success"
+	StatusFailed  = "failed"
+)
+
+type Service struct {
+	mirrorRepo    *repository.MirrorRepository
+	repoRepo      *repository.RepositoryRepository
+	gitService    *git.Service
+	quotaService  *quota.Service
+	locks         *jobs.Locks
+	syncs         *jobs.Coalescer
+	pushes        *jobs.Coalescer
+	syncListeners []func(repo *models.Repository)
+}
+
+func NewService(mirrorRepo *repository.MirrorRepository, repoRepo *repository.RepositoryRepository, gitService *git.Service, quotaService *quota.Service, locks *jobs.Locks) *Service {
+	return &Service{
+		mirrorRepo:   mirrorRepo,
+		repoRepo:     repoRepo,
+		gitService:   gitService,
+		quotaService: quotaService,
+		locks:        locks,
+		syncs:        jobs.NewCoalescer(),
+		pushes:       jobs.NewCoalescer(),
+	}
+}
+
+// OnSync registers a listener that runs after a pull mirror has fetched
+// successfully, like a push would.
+func (s *Service) OnSync(listener func(repo *models.Repository)) {
+	s.syncListeners = append(s.syncListeners, listener)
+}
+
+// Start checks for pull mirrors that are due every interval and syncs them
+// in the background, until the process exits.
+func (s *Service) Start(interval time.Duration) {
+	go func() {
+		for {
+			s.scheduleDue()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+func (s *Service) scheduleDue() {
+	mirrors, err := s.mirrorRepo.FindAllPull()
+	if err != nil {
+		fmt.Printf("Warning: Failed to list pull mirrors: %v\n", err)
+		return
+	}
+
+	now := time.Now()
+	for i := range mirrors {
+		mirror := &mirrors[i]
+		interval := time.Duration(mirror.IntervalMinutes) * time.Minute
+		if mirror.LastSyncAt == nil || now.Sub(*mirror.LastSyncAt) >= interval {
+			s.Schedule(mirror)
+		}
+	}
+}
+
+// Schedule syncs a pull mirror in the background. Requests for a mirror
+// that is already syncing are coalesced into one more run.
+func (s *Service) Schedule(mirror *models.PullMirror) {
+	repositoryID := mirror.RepositoryID
+	s.syncs.Run(repositoryID, func() {
+		// Reload so a coalesced run sees the latest URL and credentials
+		current, err := s.mirrorRepo.FindPullByRepositoryID(repositoryID)
+		if err != nil {
+			return
+		}
+		if err := s.Sync(current); err != nil {
+			fmt.Printf("Warning: Failed to sync mirror of repository %d: %v\n", repositoryID, err)
+		}
+	})
+}
+
+// Sync fetches a pull mirror from its upstream and records the outcome. It
+// does nothing once the repository has stopped being a mirror, as its
+// fetch would overwrite pushed refs, and fails while the repository or its
+// owner is over a storage limit.
+func (s *Service) Sync(mirror *models.PullMirror) error {
+	repo, err := s.repoRepo.FindByID(mirror.RepositoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !repo.IsMirror {
+		return nil
+	}
+	repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+
+	remote, err := AuthURL(mirror.URL, mirror.Username, mirror.Password)
+	if err == nil {
+		err = s.checkQuota(repo)
+	}
+	if err == nil {
+		// Maintenance waits until the fetch is done
+		unlock := s.locks.RLock(repo.ID)
+		err = s.gitService.FetchMirror(repoPath, remote)
+		unlock()
+	}
+
+	now := time.Now()
+	mirror.LastSyncAt = &now
+	if err != nil {
+		mirror.LastStatus = StatusFailed
+		mirror.LastError = Redact(err.Error(), remote, mirror.URL)
+	} else {
+		mirror.LastStatus = StatusSuccess
+		mirror.LastSuccessAt = &now
+		mirror.LastError = ""
+	}
+	if saveErr := s.mirrorRepo.UpdatePull(mirror); errors.Is(saveErr, gorm.ErrRecordNotFound) {
+		// Mirroring was stopped during the fetch
+		return nil
+	} else if saveErr != nil {
+		return saveErr
+	}
+	if err != nil {
+		return errors.New(mirror.LastError)
+	}
+
+	for _, listener := range s.syncListeners {
+		go listener(repo)
+	}
+	return nil
+}
+
+// checkQuota refuses to fetch into a repository that is over a storage
+// limit. A failed check does not stop the mirror.
+func (s *Service) checkQuota(repo *models.Repository) error {
+	var exceeded *quota.ExceededError
+	err := s.quotaService.Check(repo, 0)
+	if errors.As(err, &exceeded) {
+		return fmt.Errorf("sync skipped: %w", exceeded)
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to check storage quota of repository %d: %v\n", repo.ID, err)
+	}
+	return nil
+}
+
+// SchedulePushMirrors replicates a repository to all of its push mirrors in
+// the background. It is meant to run after every push.
+func (s *Service) SchedulePushMirrors(repo *models.Repository) {
+	mirrors, err := s.mirrorRepo.FindPushByRepositoryID(repo.ID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to list push mirrors of repository %d: %v\n", repo.ID, err)
+		return
+	}
+	for i := range mirrors {
+		s.SchedulePush(&mirrors[i])
+	}
+}
+
+// SchedulePush replicates a repository to one push mirror in the
+// background, coalescing requests while a push is running.
+func (s *Service) SchedulePush(mirror *models.PushMirror) {
+	mirrorID := mirror.ID
+	s.pushes.Run(mirrorID, func() {
+		current, err := s.mirrorRepo.FindPushByID(mirrorID)
+		if err != nil {
+			return
+		}
+		if err := s.Push(current); err != nil {
+			fmt.Printf("Warning: Failed to push mirror %d: %v\n", mirrorID, err)
+		}
+	})
+}
+
+// Push replicates a repository to a push mirror and records the outcome.
+// mirror.Repository must be loaded with its owner.
+func (s *Service) Push(mirror *models.PushMirror) error {
+	repo := &mirror.Repository
+	repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+
+	remote, err := AuthURL(mirror.URL, mirror.Username, mirror.Password)
+	if err == nil {
+		var refspecs []string
+		if mirror.OnlyProtectedBranches {
+			refspecs, err = s.protectedRefspecs(repoPath)
+		}
+		if err == nil {
+			err = s.gitService.PushMirror(repoPath, remote, refspecs)
+		}
+	}
+
+	now := time.Now()
+	mirror.LastSyncAt = &now
+	if err != nil {
+		mirror.LastStatus = StatusFailed
+		mirror.LastError = Redact(err.Error(), remote, mirror.URL)
+	} else {
+		mirror.LastStatus = StatusSuccess
+		mirror.LastSuccessAt = &now
+		mirror.LastError = ""
+	}
+	if saveErr := s.mirrorRepo.UpdatePush(mirror); errors.Is(saveErr, gorm.ErrRecordNotFound) {
+		// The mirror was removed during the push
+		return nil
+	} else if saveErr != nil {
+		return saveErr
+	}
+	if err != nil {
+		return errors.New(mirror.LastError)
+	}
+	return nil
+}
+
+// protectedRefspecs returns refspecs for the protected branches of a
+// repository. Until branch protection rules exist, the default branch is the
+// only protected branch.
+func (s *Service) protectedRefspecs(repoPath string) ([]string, error) {
+	branch, err := s.gitService.DefaultBranch(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	ref := "refs/heads/" + branch
+	return []string{"+" + ref + ":" + ref}, nil
+}
+
+// SplitCredentials removes the user info from a remote URL and returns it
+// separately, so the URL can be stored and shown without secrets.
+func SplitCredentials(rawURL string) (cleanURL, username, password string, err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", "", "", fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.User != nil {
+		username = u.User.Username()
+		password, _ = u.User.Password()
+		u.User = nil
+	}
+	return u.String(), username, password, nil
+}
+
+// AuthURL puts stored credentials back into a remote URL for git to use.
+func AuthURL(cleanURL, username, password string) (string, error) {
+	if username == "" && password == "" {
+		return cleanURL, nil
+	}
+	u, err := url.Parse(cleanURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL: %w", err)
+	}
+	u.User = url.UserPassword(username, password)
+	return u.String(), nil
+}
+
+// Redact removes the credentials in authURL from git output, replacing the
+// full URL with the clean one and dropping the user info from any other
+// mention of the remote, such as git printing it without its path. Text
+// outside URLs is left alone, so a short password cannot mangle it.
+func Redact(output, authURL, cleanURL string) string {
+	if authURL == "" || authURL == cleanURL {
+		return output
+	}
+	output = strings.ReplaceAll(output, authURL, cleanURL)
+	u, err := url.Parse(authURL)
+	if err != nil || u.User == nil {
+		return output
+	}
+	userinfo := []string{u.User.String(), u.User.Username()}
+	if password, ok := u.User.Password(); ok {
+		userinfo = append(userinfo, u.User.Username()+":"+password)
+	}
+	for _, info := range userinfo {
+		output = strings.ReplaceAll(output, "//"+info+"@", "//")
+	}
+	return output
+}
@@ -25,11 +25,245 @@ type Repository struct {
 	Name        string         `json:"name" gorm:"not null"`
 	Description string         `json:"description"`
 	Visibility  string         `json:"visibility" gorm:"default:'private'"`
+	Topics      []string       `json:"topics" gorm:"serializer:json;type:text"`
+	StarsCount  int            `json:"stars_count" gorm:"not null;default:0"`
 	OwnerID     uint           `json:"owner_id" gorm:"not null"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
 	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
 
+	// Settings
+	Homepage         string   `json:"homepage"`
+	Archived         bool     `json:"archived" gorm:"not null;default:false"`
+	IsTemplate       bool     `json:"is_template" gorm:"not null;default:false"`
+	IsMirror         bool     `json:"is_mirror" gorm:"not null;default:false"`
+	HasIssues        bool     `json:"has_issues" gorm:"not null;default:true"`
+	HasWiki          bool     `json:"has_wiki" gorm:"not null;default:true"`
+	HasMergeRequests bool     `json:"has_merge_requests" gorm:"not null;default:true"`
+	MergeStrategies  []string `json:"merge_strategies" gorm:"serializer:json;type:text"`
+
+	// LanguagesComputedAt is set once the language breakdown has been
+	// computed, so repositories without any detected language are not
+	// analysed again on every request
+	LanguagesComputedAt *time.Time `json:"-"`
+
+	// Relationships
+	Owner     User                 `json:"owner" gorm:"foreignKey:OwnerID"`
+	Languages []RepositoryLanguage `json:"languages,omitempty" gorm:"foreignKey:RepositoryID"`
+}
+
+// RepositoryLanguage is the number of bytes of a language on the default
+// branch of a repository.
+type RepositoryLanguage struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	RepositoryID uint      `json:"-" gorm:"not null;uniqueIndex:idx_repository_language"`
+	Language     string    `json:"language" gorm:"not null;uniqueIndex:idx_repository_language"`
+	Bytes        int64     `json:"bytes" gorm:"not null"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type Star struct {
+	UserID       uint      `json:"user_id" gorm:"primaryKey"`
+	RepositoryID uint      `json:"repository_id" gorm:"primaryKey"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RepositoryRedirect keeps an old owner/name path of a renamed or
+// transferred repository pointing at its current location.
+type RepositoryRedirect struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	OwnerName    string    `json:"owner_name" gorm:"not null;uniqueIndex:idx_redirect_path"`
+	Name         string    `json:"name" gorm:"not null;uniqueIndex:idx_redirect_path"`
+	RepositoryID uint      `json:"repository_id" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RepositoryTransfer is a pending change of owner that waits for the new
+// owner to accept it.
+type RepositoryTransfer struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	RepositoryID uint      `json:"repository_id" gorm:"not null;uniqueIndex"`
+	FromUserID   uint      `json:"from_user_id" gorm:"not null"`
+	ToUserID     uint      `json:"to_user_id" gorm:"not null;index"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	// Relationships
+	Repository Repository `json:"repository" gorm:"foreignKey:RepositoryID"`
+	FromUser   User       `json:"from_user" gorm:"foreignKey:FromUserID"`
+	ToUser     User       `json:"to_user" gorm:"foreignKey:ToUserID"`
+}
+
+// PullMirror keeps a repository in sync with an upstream repository that it
+// fetches from periodically. Credentials are kept out of URL so it can be
+// shown to users.
+type PullMirror struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	RepositoryID    uint       `json:"repository_id" gorm:"not null;uniqueIndex"`
+	URL             string     `json:"url" gorm:"not null"`
+	Username        string     `json:"-"`
+	Password        string     `json:"-"`
+	IntervalMinutes int        `json:"interval_minutes" gorm:"not null"`
+	LastSyncAt      *time.Time `json:"last_sync_at"`
+	LastSuccessAt   *time.Time `json:"last_success_at"`
+	LastStatus      string     `json:"last_status" gorm:"not null;default:'pending'"` // pending, success, failed
+	LastError       string     `json:"last_error"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+
+	// Relationships
+	Repository Repository `json:"-" gorm:"foreignKey:RepositoryID"`
+}
+
+// PushMirror replicates a repository to a secondary remote after every push.
+type PushMirror struct {
+	ID           uint   `json:"id" gorm:"primaryKey"`
+	RepositoryID uint   `json:"repository_id" gorm:"not null;index"`
+	URL          string `json:"url" gorm:"not null"`
+	Username     string `json:"-"`
+	Password     string `json:"-"`
+	// OnlyProtectedBranches limits the mirror to protected branches instead
+	// of all refs
+	OnlyProtectedBranches bool       `json:"only_protected_branches" gorm:"not null;default:false"`
+	LastSyncAt            *time.Time `json:"last_sync_at"`
+	LastSuccessAt         *time.Time `json:"last_success_at"`
+	LastStatus            string     `json:"last_status" gorm:"not null;default:'pending'"` // pending, success, failed
+	LastError             string     `json:"last_error"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+
+	// Relationships
+	Repository Repository `json:"-" gorm:"foreignKey:RepositoryID"`
+}
+
+// RepositoryImport tracks the creation of a repository from existing
+// history. When an import fails its repository is removed and RepositoryID
+// is cleared.
+type RepositoryImport struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	RepositoryID *uint      `json:"repository_id" gorm:"index"`
+	UserID       uint       `json:"user_id" gorm:"not null;index"`
+	Name         string     `json:"name" gorm:"not null"`
+	Source       string     `json:"source" gorm:"not null"` // url, bundle, archive
+	URL          string     `json:"url,omitempty"`
+	Username     string     `json:"-"`
+	Password     string     `json:"-"`
+	Status       string     `json:"status" gorm:"not null;default:'pending'"` // pending, running, success, failed
+	Error        string     `json:"error"`
+	StartedAt    *time.Time `json:"started_at"`
+	FinishedAt   *time.Time `json:"finished_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// LFSObject records that a repository references a Git LFS object. The
+// content is stored once per object ID, however many repositories use it.
+type LFSObject struct {
+	ID           uint      `json:"-" gorm:"primaryKey"`
+	RepositoryID uint      `json:"-" gorm:"not null;uniqueIndex:idx_lfs_object"`
+	OID          string    `json:"oid" gorm:"column:oid;not null;size:64;uniqueIndex:idx_lfs_object"`
+	Size         int64     `json:"size" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// LFSLock marks a file as being edited by one user, so others know not to
+// change it.
+type LFSLock struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	RepositoryID uint      `json:"repository_id" gorm:"not null;uniqueIndex:idx_lfs_lock"`
+	Path         string    `json:"path" gorm:"not null;uniqueIndex:idx_lfs_lock"`
+	OwnerID      uint      `json:"owner_id" gorm:"not null"`
+	CreatedAt    time.Time `json:"locked_at"`
+
+	// Relationships
+	Owner User `json:"owner" gorm:"foreignKey:OwnerID"`
+}
+
+// RepositoryStorage is the disk space used by the git data of a repository
+// and its wiki, measured after pushes. LFS objects are summed from their
+// LFSObject rows instead.
+type RepositoryStorage struct {
+	RepositoryID uint      `json:"repository_id" gorm:"primaryKey;autoIncrement:false"`
+	Size         int64     `json:"size" gorm:"not null"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// StorageLimit overrides the server-wide storage limits for the repositories
+// of one user. A nil limit falls back to the server default and zero means
+// unlimited.
+type StorageLimit struct {
+	UserID          uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Quota           *int64    `json:"quota"`
+	RepositoryLimit *int64    `json:"repository_limit"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// RepositoryHousekeeping counts the pushes to a repository since it was last
+// maintained and records the result of its last maintenance run.
+type RepositoryHousekeeping struct {
+	RepositoryID      uint       `json:"repository_id" gorm:"primaryKey;autoIncrement:false"`
+	PushesSinceRepack int        `json:"pushes_since_repack" gorm:"not null;default:0"`
+	PushesSinceGC     int        `json:"pushes_since_gc" gorm:"not null;default:0"`
+	LastTask          string     `json:"last_task"`   // incremental, full
+	LastStatus        string     `json:"last_status"` // running, success, failed
+	LastError         string     `json:"last_error"`
+	LastStartedAt     *time.Time `json:"last_started_at"`
+	LastFinishedAt    *time.Time `json:"last_finished_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// IntegrityIssue is an inconsistency between the database and the
+// repositories on disk, found by the last integrity check.
+type IntegrityIssue struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	Kind         string     `json:"kind" gorm:"not null"` // missing, orphan, corrupt
+	RepositoryID *uint      `json:"repository_id" gorm:"index"`
+	Path         string     `json:"path" gorm:"not null"` // relative to the repositories root
+	Details      string     `json:"details"`
+	Resolution   string     `json:"resolution"` // repaired, quarantined
+	Outcome      string     `json:"outcome"`    // what the fix did
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// AuditEvent records a security-relevant change, such as a repository
+// becoming public.
+type AuditEvent struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	ActorID    uint      `json:"actor_id" gorm:"not null;index"`
+	Action     string    `json:"action" gorm:"not null;index"`
+	TargetType string    `json:"target_type" gorm:"not null"`
+	TargetID   uint      `json:"target_id" gorm:"not null;index"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type CommitStatus struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	RepositoryID uint           `json:"repository_id" gorm:"not null;index:idx_commit_status_sha"`
+	SHA          string         `json:"sha" gorm:"not null;size:40;index:idx_commit_status_sha"`
+	State        string         `json:"state" gorm:"not null"` // pending, success, failure, error
+	Context      string         `json:"context" gorm:"not null;default:'default'"`
+	Description  string         `json:"description"`
+	TargetURL    string         `json:"target_url"`
+	CreatorID    uint           `json:"creator_id" gorm:"not null"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Creator User `json:"creator" gorm:"foreignKey:CreatorID"`
+}
+
+type Snippet struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Title       string         `json:"title" gorm:"not null"`
+	Description string         `json:"description"`
+	Visibility  string         `json:"visibility" gorm:"default:'private'"`
+	OwnerID     uint           `json:"owner_id" gorm:"not null;index"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
+
 	// Relationships
 	Owner User `json:"owner" gorm:"foreignKey:OwnerID"`
 }
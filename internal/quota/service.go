@@ -0,0 +1,296 @@
+// Package quota measures the storage used by repositories and enforces
+// per-user quotas and per-repository size limits.
+package quota
+
+import (
+	"errors"
+	"fmt"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+
+	"gorm.io/gorm"
+)
+
+// Limits are storage limits in bytes. Zero means unlimited.
+type Limits struct {
+	// Quota caps the total storage of all repositories of a user
+	Quota int64 `json:"quota"`
+	// RepositoryLimit caps the storage of each repository
+	RepositoryLimit int64 `json:"repository_limit"`
+}
+
+// ExceededError reports that a repository or its owner has reached a limit.
+type ExceededError struct {
+	// Repository is true when the repository size limit was reached rather
+	// than the owner's quota
+	Repository bool
+	Used       int64
+	// Requested is the size that was about to be added, zero for pushes
+	Requested int64
+	Limit     int64
+}
+
+func (e *ExceededError) Error() string {
+	name := "storage quota"
+	if e.Repository {
+		name = "repository size limit"
+	}
+	if e.Requested > 0 {
+		return fmt.Sprintf("%s of %s would be exceeded (%s used, %s more requested)", name, FormatSize(e.Limit), FormatSize(e.Used), FormatSize(e.Requested))
+	}
+	return fmt.Sprintf("%s reached (%s of %s used)", name, FormatSize(e.Used), FormatSize(e.Limit))
+}
+
+// Usage is the storage used by the repositories of a user.
+type Usage struct {
+	UserID uint `json:"user_id"`
+	Limits
+	// Overridden is true when an administrator has set limits for the user
+	Overridden   bool                         `json:"overridden"`
+	Used         int64                        `json:"used"`
+	Repositories []repository.RepositoryUsage `json:"repositories"`
+}
+
+type Service struct {
+	storageRepo *repository.StorageRepository
+	gitService  *git.Service
+	defaults    Limits
+	refreshes   *jobs.Coalescer
+}
+
+func NewService(storageRepo *repository.StorageRepository, gitService *git.Service, defaults Limits) *Service {
+	return &Service{
+		storageRepo: storageRepo,
+		gitService:  gitService,
+		defaults:    defaults,
+		refreshes:   jobs.NewCoalescer(),
+	}
+}
+
+// Defaults returns the limits of users without an override.
+func (s *Service) Defaults() Limits {
+	return s.defaults
+}
+
+// Measure returns the disk space used by the git data of a repository and
+// its wiki.
+func (s *Service) Measure(repo *models.Repository) (int64, error) {
+	size, err := s.gitService.RepositorySize(s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name))
+	if err != nil {
+		return 0, err
+	}
+	if s.gitService.WikiExists(repo.Owner.Username, repo.Name) {
+		wikiSize, err := s.gitService.RepositorySize(s.gitService.GetWikiPath(repo.Owner.Username, repo.Name))
+		if err != nil {
+			return 0, err
+		}
+		size += wikiSize
+	}
+	return size, nil
+}
+
+// Refresh measures a repository and stores its size.
+func (s *Service) Refresh(repo *models.Repository) error {
+	size, err := s.Measure(repo)
+	if err != nil {
+		return fmt.Errorf("failed to measure repository: %w", err)
+	}
+	return s.storageRepo.SaveSize(repo.ID, size)
+}
+
+// Schedule measures a repository in the background. Pushes that arrive while
+// a measurement is running are coalesced into a single follow-up run.
+func (s *Service) Schedule(repo *models.Repository) {
+	s.refreshes.Run(repo.ID, func() {
+		if err := s.Refresh(repo); err != nil {
+			fmt.Printf("Warning: Failed to measure storage of repository %d: %v\n", repo.ID, err)
+		}
+	})
+}
+
+// Limits returns the limits that apply to the repositories of a user and
+// whether an administrator has overridden the defaults.
+func (s *Service) Limits(userID uint) (Limits, bool, error) {
+	limits := s.defaults
+	override, err := s.storageRepo.FindLimit(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return limits, false, nil
+	}
+	if err != nil {
+		return Limits{}, false, err
+	}
+	if override.Quota != nil {
+		limits.Quota = *override.Quota
+	}
+	if override.RepositoryLimit != nil {
+		limits.RepositoryLimit = *override.RepositoryLimit
+	}
+	return limits, true, nil
+}
+
+// Usage returns the storage used by the repositories of a user along with
+// the limits that apply to them.
+func (s *Service) Usage(userID uint) (*Usage, error) {
+	limits, overridden, err := s.Limits(userID)
+	if err != nil {
+		return nil, err
+	}
+	repos, err := s.storageRepo.FindUsage(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &Usage{UserID: userID, Limits: limits, Overridden: overridden, Repositories: repos}
+	for _, repo := range repos {
+		usage.Used += repo.Total()
+	}
+	return usage, nil
+}
+
+// Check returns an *ExceededError when adding size bytes to a repository
+// would take it or its owner past a limit. Pushes pass zero: their size is
+// only known once they have been received, so a push is refused when the
+// limit has already been reached and the push that crosses it is accepted.
+func (s *Service) Check(repo *models.Repository, size int64) error {
+	usage, err := s.repositoryUsage(repo)
+	if err != nil {
+		return err
+	}
+	return usage.Check(repo.ID, size)
+}
+
+// CheckOwner returns an *ExceededError when a user has reached their quota.
+// It guards operations that create a repository and fill it in one go, such
+// as imports, mirrors and repositories generated from templates.
+func (s *Service) CheckOwner(userID uint) error {
+	usage, err := s.Usage(userID)
+	if err != nil {
+		return err
+	}
+	return usage.Check(0, 0)
+}
+
+// Remaining returns how many more bytes a repository can store before it or
+// its owner reaches a limit. limited is false when neither has a limit.
+func (s *Service) Remaining(repo *models.Repository) (remaining int64, limited bool, err error) {
+	usage, err := s.repositoryUsage(repo)
+	if err != nil {
+		return 0, false, err
+	}
+	remaining, limited = usage.Remaining(repo.ID)
+	return remaining, limited, nil
+}
+
+// repositoryUsage returns the usage of the owner of repo. Repositories
+// created before quotas existed are measured on demand.
+func (s *Service) repositoryUsage(repo *models.Repository) (*Usage, error) {
+	usage, err := s.Usage(repo.OwnerID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range usage.Repositories {
+		repoUsage := &usage.Repositories[i]
+		if repoUsage.RepositoryID != repo.ID || repoUsage.Measured {
+			continue
+		}
+		measured, err := s.Measure(repo)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.storageRepo.SaveSize(repo.ID, measured); err != nil {
+			return nil, err
+		}
+		usage.Used += measured - repoUsage.Size
+		repoUsage.Size, repoUsage.Measured = measured, true
+	}
+	return usage, nil
+}
+
+// Check is Service.Check for usage that has already been loaded.
+func (u *Usage) Check(repositoryID uint, size int64) error {
+	repoUsed := u.repositoryUsed(repositoryID)
+	if exceeds(repoUsed, size, u.RepositoryLimit) {
+		return &ExceededError{Repository: true, Used: repoUsed, Requested: size, Limit: u.RepositoryLimit}
+	}
+	if exceeds(u.Used, size, u.Quota) {
+		return &ExceededError{Used: u.Used, Requested: size, Limit: u.Quota}
+	}
+	return nil
+}
+
+// Remaining is Service.Remaining for usage that has already been loaded.
+func (u *Usage) Remaining(repositoryID uint) (remaining int64, limited bool) {
+	for _, usedLimit := range [][2]int64{
+		{u.repositoryUsed(repositoryID), u.RepositoryLimit},
+		{u.Used, u.Quota},
+	} {
+		if usedLimit[1] == 0 {
+			continue
+		}
+		left := max(usedLimit[1]-usedLimit[0], 0)
+		if !limited || left < remaining {
+			remaining = left
+		}
+		limited = true
+	}
+	return remaining, limited
+}
+
+func (u *Usage) repositoryUsed(repositoryID uint) int64 {
+	for _, repoUsage := range u.Repositories {
+		if repoUsage.RepositoryID == repositoryID {
+			return repoUsage.Total()
+		}
+	}
+	return 0
+}
+
+func exceeds(used, size, limit int64) bool {
+	if limit == 0 {
+		return false
+	}
+	if size == 0 {
+		return used >= limit
+	}
+	return used+size > limit
+}
+
+// MeasureMissing measures every repository that has no recorded size yet,
+// such as those created before quotas were introduced.
+func (s *Service) MeasureMissing(repos []models.Repository) {
+	usage, err := s.storageRepo.FindUsage(0)
+	if err != nil {
+		fmt.Printf("Warning: Failed to load repository storage: %v\n", err)
+		return
+	}
+	measured := make(map[uint]bool, len(usage))
+	for _, repoUsage := range usage {
+		measured[repoUsage.RepositoryID] = repoUsage.Measured
+	}
+
+	for i := range repos {
+		if measured[repos[i].ID] {
+			continue
+		}
+		if err := s.Refresh(&repos[i]); err != nil {
+			fmt.Printf("Warning: Failed to measure storage of repository %d: %v\n", repos[i].ID, err)
+		}
+	}
+}
+
+// FormatSize renders a byte count for messages shown to users.
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit && exp < 4; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTP"[exp])
+}
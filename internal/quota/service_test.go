@@ -0,0 +1,105 @@
+package quota
+
+import (
+	"errors"
+	"testing"
+
+	"gitlab-tool/internal/repository"
+)
+
+func TestExceeds(t *testing.T) {
+	tests := []struct {
+		used, size, limit int64
+		want              bool
+	}{
+		{used: 100, size: 0, limit: 0, want: false},
+		{used: 99, size: 0, limit: 100, want: false},
+		{used: 100, size: 0, limit: 100, want: true},
+		{used: 90, size: 10, limit: 100, want: false},
+		{used: 90, size: 11, limit: 100, want: true},
+	}
+	for _, tt := range tests {
+		if got := exceeds(tt.used, tt.size, tt.limit); got != tt.want {
+			t.Errorf("exceeds(%d, %d, %d) = %v, want %v", tt.used, tt.size, tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for bytes, want := range map[int64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1536:             "1.5 KiB",
+		10 << 20:         "10.0 MiB",
+		3 << 30:          "3.0 GiB",
+		5 << 40:          "5.0 TiB",
+		(1 << 50) * 2048: "2048.0 PiB",
+	} {
+		if got := FormatSize(bytes); got != want {
+			t.Errorf("FormatSize(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
+
+func TestUsageCheck(t *testing.T) {
+	usage := &Usage{
+		Limits: Limits{Quota: 1000, RepositoryLimit: 400},
+		Used:   900,
+		Repositories: []repository.RepositoryUsage{
+			{RepositoryID: 1, Size: 300, LFSSize: 50, Measured: true},
+			{RepositoryID: 2, Size: 550, Measured: true},
+		},
+	}
+	tests := []struct {
+		repositoryID uint
+		size         int64
+		repository   bool
+		exceeded     bool
+	}{
+		{repositoryID: 1, size: 50},
+		{repositoryID: 1, size: 51, repository: true, exceeded: true},
+		{repositoryID: 2, size: 0, repository: true, exceeded: true},
+		{repositoryID: 3, size: 100},
+		{repositoryID: 3, size: 101, exceeded: true},
+	}
+	for _, tt := range tests {
+		err := usage.Check(tt.repositoryID, tt.size)
+		var exceeded *ExceededError
+		if !errors.As(err, &exceeded) {
+			if tt.exceeded {
+				t.Errorf("Check(%d, %d) = %v, want exceeded", tt.repositoryID, tt.size, err)
+			}
+			continue
+		}
+		if !tt.exceeded || exceeded.Repository != tt.repository {
+			t.Errorf("Check(%d, %d) = %+v, want repository limit %v exceeded %v", tt.repositoryID, tt.size, exceeded, tt.repository, tt.exceeded)
+		}
+	}
+
+	if err := (&Usage{Used: 1 << 40}).Check(1, 1<<40); err != nil {
+		t.Errorf("Check without limits = %v", err)
+	}
+}
+
+func TestUsageRemaining(t *testing.T) {
+	repos := []repository.RepositoryUsage{{RepositoryID: 1, Size: 300, LFSSize: 50}}
+	tests := []struct {
+		limits  Limits
+		used    int64
+		want    int64
+		limited bool
+	}{
+		{limits: Limits{}, used: 900},
+		{limits: Limits{RepositoryLimit: 400}, used: 900, want: 50, limited: true},
+		{limits: Limits{Quota: 1000}, used: 900, want: 100, limited: true},
+		{limits: Limits{Quota: 1000, RepositoryLimit: 400}, used: 900, want: 50, limited: true},
+		{limits: Limits{Quota: 1000, RepositoryLimit: 400}, used: 980, want: 20, limited: true},
+		{limits: Limits{Quota: 1000}, used: 1200, want: 0, limited: true},
+	}
+	for _, tt := range tests {
+		usage := &Usage{Limits: tt.limits, Used: tt.used, Repositories: repos}
+		if got, limited := usage.Remaining(1); got != tt.want || limited != tt.limited {
+			t.Errorf("Remaining with %+v and %d used = %d, %v, want %d, %v", tt.limits, tt.used, got, limited, tt.want, tt.limited)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) Create(event *models.AuditEvent) error {
+	return r.db.Create(event).Error
+}
+
+func (r *AuditRepository) FindByTarget(targetType string, targetID uint) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	err := r.db.Where("target_type = ? AND target_id = ?", targetType, targetID).Order("created_at DESC").Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
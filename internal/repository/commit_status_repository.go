@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type CommitStatusRepository struct {
+	db *gorm.DB
+}
+
+func NewCommitStatusRepository(db *gorm.DB) *CommitStatusRepository {
+	return &CommitStatusRepository{db: db}
+}
+
+func (r *CommitStatusRepository) Create(status *models.CommitStatus) error {
+	return r.db.Create(status).Error
+}
+
+// FindBySHA returns every status reported for a commit, newest first.
+func (r *CommitStatusRepository) FindBySHA(repositoryID uint, sha string) ([]models.CommitStatus, error) {
+	var statuses []models.CommitStatus
+	err := r.db.Where("repository_id = ? AND sha = ?", repositoryID, sha).
+		Preload("Creator").
+		Order("created_at DESC, id DESC").
+		Find(&statuses).Error
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// FindByRepositoryID returns every status reported in a repository, oldest
+// first.
+func (r *CommitStatusRepository) FindByRepositoryID(repositoryID uint) ([]models.CommitStatus, error) {
+	var statuses []models.CommitStatus
+	err := r.db.Where("repository_id = ?", repositoryID).
+		Preload("Creator").
+		Order("created_at, id").
+		Find(&statuses).Error
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// FindLatestBySHA returns the most recent status for each context of a commit.
+func (r *CommitStatusRepository) FindLatestBySHA(repositoryID uint, sha string) ([]models.CommitStatus, error) {
+	statuses, err := r.FindBySHA(repositoryID, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	latest := []models.CommitStatus{}
+	for _, status := range statuses {
+		if seen[status.Context] {
+			continue
+		}
+		seen[status.Context] = true
+		latest = append(latest, status)
+	}
+	return latest, nil
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"time"
+
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type HousekeepingRepository struct {
+	db *gorm.DB
+}
+
+func NewHousekeepingRepository(db *gorm.DB) *HousekeepingRepository {
+	return &HousekeepingRepository{db: db}
+}
+
+// Find returns the housekeeping record of a repository, creating an empty one
+// for repositories that have never been pushed to or maintained.
+func (r *HousekeepingRepository) Find(repositoryID uint) (*models.RepositoryHousekeeping, error) {
+	var record models.RepositoryHousekeeping
+	err := r.db.Where(models.RepositoryHousekeeping{RepositoryID: repositoryID}).FirstOrCreate(&record).Error
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// CountPush records a push to a repository.
+func (r *HousekeepingRepository) CountPush(repositoryID uint) error {
+	if _, err := r.Find(repositoryID); err != nil {
+		return err
+	}
+	return r.db.Model(&models.RepositoryHousekeeping{}).Where("repository_id = ?", repositoryID).
+		UpdateColumns(map[string]interface{}{
+			"pushes_since_repack": gorm.Expr("pushes_since_repack + 1"),
+			"pushes_since_gc":     gorm.Expr("pushes_since_gc + 1"),
+		}).Error
+}
+
+// Start marks a maintenance run as started and resets the push counters it
+// covers: a full run counts as a repack too.
+func (r *HousekeepingRepository) Start(repositoryID uint, task string, full bool) error {
+	if _, err := r.Find(repositoryID); err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"last_task":           task,
+		"last_status":         "running",
+		"last_error":          "",
+		"last_started_at":     time.Now(),
+		"last_finished_at":    nil,
+		"pushes_since_repack": 0,
+		"updated_at":          time.Now(),
+	}
+	if full {
+		updates["pushes_since_gc"] = 0
+	}
+	return r.db.Model(&models.RepositoryHousekeeping{}).Where("repository_id = ?", repositoryID).UpdateColumns(updates).Error
+}
+
+// Finish records the outcome of a maintenance run.
+func (r *HousekeepingRepository) Finish(repositoryID uint, status, message string) error {
+	return r.db.Model(&models.RepositoryHousekeeping{}).Where("repository_id = ?", repositoryID).
+		UpdateColumns(map[string]interface{}{
+			"last_status":      status,
+			"last_error":       message,
+			"last_finished_at": time.Now(),
+			"updated_at":       time.Now(),
+		}).Error
+}
+
+// FindRunning returns the records of runs that never finished, such as those
+// interrupted by a restart.
+func (r *HousekeepingRepository) FindRunning() ([]models.RepositoryHousekeeping, error) {
+	var records []models.RepositoryHousekeeping
+	if err := r.db.Where("last_status = ?", "running").Find(&records).Error; err != nil {
+		return nil, err
+	}
+	return records, nil
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type ImportRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRepository(db *gorm.DB) *ImportRepository {
+	return &ImportRepository{db: db}
+}
+
+func (r *ImportRepository) Create(imp *models.RepositoryImport) error {
+	return r.db.Create(imp).Error
+}
+
+func (r *ImportRepository) FindByID(id uint) (*models.RepositoryImport, error) {
+	var imp models.RepositoryImport
+	if err := r.db.First(&imp, id).Error; err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// FindUnfinished returns imports that are pending or running.
+func (r *ImportRepository) FindUnfinished() ([]models.RepositoryImport, error) {
+	var imports []models.RepositoryImport
+	err := r.db.Where("status IN ?", []string{"pending", "running"}).Find(&imports).Error
+	if err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// IsImporting reports whether a repository has an unfinished import.
+func (r *ImportRepository) IsImporting(repositoryID uint) bool {
+	var count int64
+	r.db.Model(&models.RepositoryImport{}).
+		Where("repository_id = ? AND status IN ?", repositoryID, []string{"pending", "running"}).
+		Count(&count)
+	return count > 0
+}
+
+func (r *ImportRepository) Update(imp *models.RepositoryImport) error {
+	return r.db.Save(imp).Error
+}
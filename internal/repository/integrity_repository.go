@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"time"
+
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type IntegrityRepository struct {
+	db *gorm.DB
+}
+
+func NewIntegrityRepository(db *gorm.DB) *IntegrityRepository {
+	return &IntegrityRepository{db: db}
+}
+
+// Replace swaps the findings of the previous check for those of a new one.
+func (r *IntegrityRepository) Replace(issues []models.IntegrityIssue) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.IntegrityIssue{}).Error; err != nil {
+			return err
+		}
+		if len(issues) == 0 {
+			return nil
+		}
+		return tx.Create(&issues).Error
+	})
+}
+
+func (r *IntegrityRepository) FindAll() ([]models.IntegrityIssue, error) {
+	var issues []models.IntegrityIssue
+	if err := r.db.Order("id").Find(&issues).Error; err != nil {
+		return nil, err
+	}
+	return issues, nil
+}
+
+func (r *IntegrityRepository) FindByID(id uint) (*models.IntegrityIssue, error) {
+	var issue models.IntegrityIssue
+	if err := r.db.First(&issue, id).Error; err != nil {
+		return nil, err
+	}
+	return &issue, nil
+}
+
+// Resolve records how an issue was dealt with.
+func (r *IntegrityRepository) Resolve(issue *models.IntegrityIssue, resolution, outcome string) error {
+	now := time.Now()
+	issue.Resolution = resolution
+	issue.Outcome = outcome
+	issue.ResolvedAt = &now
+	return r.db.Save(issue).Error
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"time"
+
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type LanguageRepository struct {
+	db *gorm.DB
+}
+
+func NewLanguageRepository(db *gorm.DB) *LanguageRepository {
+	return &LanguageRepository{db: db}
+}
+
+// FindByRepositoryID returns the language breakdown of a repository, largest first.
+func (r *LanguageRepository) FindByRepositoryID(repositoryID uint) ([]models.RepositoryLanguage, error) {
+	var languages []models.RepositoryLanguage
+	err := r.db.Where("repository_id = ?", repositoryID).Order("bytes DESC").Find(&languages).Error
+	if err != nil {
+		return nil, err
+	}
+	return languages, nil
+}
+
+// Replace swaps the stored breakdown of a repository for a freshly computed
+// one and records when it was computed.
+func (r *LanguageRepository) Replace(repositoryID uint, bytesByLanguage map[string]int64) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("repository_id = ?", repositoryID).Delete(&models.RepositoryLanguage{}).Error; err != nil {
+			return err
+		}
+		for language, bytes := range bytesByLanguage {
+			row := &models.RepositoryLanguage{RepositoryID: repositoryID, Language: language, Bytes: bytes}
+			if err := tx.Create(row).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Repository{}).Where("id = ?", repositoryID).
+			UpdateColumn("languages_computed_at", time.Now()).Error
+	})
+}
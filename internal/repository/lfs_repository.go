@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type LFSRepository struct {
+	db *gorm.DB
+}
+
+func NewLFSRepository(db *gorm.DB) *LFSRepository {
+	return &LFSRepository{db: db}
+}
+
+// LinkObject records that a repository references an object. Linking an
+// object twice is a no-op.
+func (r *LFSRepository) LinkObject(repositoryID uint, oid string, size int64) error {
+	return r.db.Where(models.LFSObject{RepositoryID: repositoryID, OID: oid}).
+		FirstOrCreate(&models.LFSObject{RepositoryID: repositoryID, OID: oid, Size: size}).Error
+}
+
+func (r *LFSRepository) FindObject(repositoryID uint, oid string) (*models.LFSObject, error) {
+	var object models.LFSObject
+	err := r.db.Where("repository_id = ? AND oid = ?", repositoryID, oid).First(&object).Error
+	if err != nil {
+		return nil, err
+	}
+	return &object, nil
+}
+
+// LFSUsage is the storage used by the LFS objects of a repository.
+type LFSUsage struct {
+	Objects int64 `json:"objects"`
+	Size    int64 `json:"size"`
+}
+
+func (r *LFSRepository) Usage(repositoryID uint) (LFSUsage, error) {
+	var usage LFSUsage
+	err := r.db.Model(&models.LFSObject{}).
+		Select("COUNT(*) AS objects, COALESCE(SUM(size), 0) AS size").
+		Where("repository_id = ?", repositoryID).
+		Scan(&usage).Error
+	return usage, err
+}
+
+func (r *LFSRepository) CreateLock(lock *models.LFSLock) error {
+	return r.db.Create(lock).Error
+}
+
+func (r *LFSRepository) FindLockByID(repositoryID, id uint) (*models.LFSLock, error) {
+	var lock models.LFSLock
+	err := r.db.Preload("Owner").Where("repository_id = ?", repositoryID).First(&lock, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (r *LFSRepository) FindLockByPath(repositoryID uint, path string) (*models.LFSLock, error) {
+	var lock models.LFSLock
+	err := r.db.Preload("Owner").Where("repository_id = ? AND path = ?", repositoryID, path).First(&lock).Error
+	if err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// LockSearch filters the locks of a repository. Locks are ordered by ID and
+// AfterID pages through them.
+type LockSearch struct {
+	Path    string
+	ID      uint
+	AfterID uint
+	Limit   int
+}
+
+func (r *LFSRepository) FindLocks(repositoryID uint, search LockSearch) ([]models.LFSLock, error) {
+	query := r.db.Preload("Owner").Where("repository_id = ? AND id > ?", repositoryID, search.AfterID)
+	if search.Path != "" {
+		query = query.Where("path = ?", search.Path)
+	}
+	if search.ID != 0 {
+		query = query.Where("id = ?", search.ID)
+	}
+
+	var locks []models.LFSLock
+	err := query.Order("id").Limit(search.Limit).Find(&locks).Error
+	if err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+func (r *LFSRepository) DeleteLock(id uint) error {
+	return r.db.Delete(&models.LFSLock{}, id).Error
+}
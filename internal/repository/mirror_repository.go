@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"time"
+
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type MirrorRepository struct {
+	db *gorm.DB
+}
+
+func NewMirrorRepository(db *gorm.DB) *MirrorRepository {
+	return &MirrorRepository{db: db}
+}
+
+func (r *MirrorRepository) CreatePull(mirror *models.PullMirror) error {
+	return r.db.Create(mirror).Error
+}
+
+func (r *MirrorRepository) FindPullByRepositoryID(repositoryID uint) (*models.PullMirror, error) {
+	var mirror models.PullMirror
+	err := r.db.Preload("Repository.Owner").Where("repository_id = ?", repositoryID).First(&mirror).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mirror, nil
+}
+
+// FindAllPull returns every pull mirror whose repository has not been
+// deleted.
+func (r *MirrorRepository) FindAllPull() ([]models.PullMirror, error) {
+	var mirrors []models.PullMirror
+	err := r.db.Joins("JOIN repositories ON repositories.id = pull_mirrors.repository_id AND repositories.deleted_at IS NULL").
+		Preload("Repository.Owner").
+		Find(&mirrors).Error
+	if err != nil {
+		return nil, err
+	}
+	return mirrors, nil
+}
+
+// UpdatePull records the outcome of a sync. It returns
+// gorm.ErrRecordNotFound if the mirror has been removed in the meantime,
+// rather than bringing it back.
+func (r *MirrorRepository) UpdatePull(mirror *models.PullMirror) error {
+	return updateSyncStatus(r.db.Model(&models.PullMirror{}), mirror.ID,
+		mirror.LastSyncAt, mirror.LastSuccessAt, mirror.LastStatus, mirror.LastError)
+}
+
+func (r *MirrorRepository) DeletePull(repositoryID uint) error {
+	return r.db.Where("repository_id = ?", repositoryID).Delete(&models.PullMirror{}).Error
+}
+
+func (r *MirrorRepository) CreatePush(mirror *models.PushMirror) error {
+	return r.db.Create(mirror).Error
+}
+
+func (r *MirrorRepository) FindPushByID(id uint) (*models.PushMirror, error) {
+	var mirror models.PushMirror
+	err := r.db.Preload("Repository.Owner").First(&mirror, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mirror, nil
+}
+
+func (r *MirrorRepository) FindPushByRepositoryID(repositoryID uint) ([]models.PushMirror, error) {
+	var mirrors []models.PushMirror
+	err := r.db.Where("repository_id = ?", repositoryID).Order("id").Find(&mirrors).Error
+	if err != nil {
+		return nil, err
+	}
+	return mirrors, nil
+}
+
+// UpdatePush records the outcome of a push. Like UpdatePull it returns
+// gorm.ErrRecordNotFound for a mirror that has been removed meanwhile.
+func (r *MirrorRepository) UpdatePush(mirror *models.PushMirror) error {
+	return updateSyncStatus(r.db.Model(&models.PushMirror{}), mirror.ID,
+		mirror.LastSyncAt, mirror.LastSuccessAt, mirror.LastStatus, mirror.LastError)
+}
+
+func (r *MirrorRepository) DeletePush(id uint) error {
+	return r.db.Delete(&models.PushMirror{}, id).Error
+}
+
+// updateSyncStatus writes only the status columns of a mirror, so a mirror
+// deleted while it was syncing stays deleted.
+func updateSyncStatus(db *gorm.DB, id uint, lastSyncAt, lastSuccessAt *time.Time, status, lastError string) error {
+	result := db.Where("id = ?", id).Updates(map[string]interface{}{
+		"last_sync_at":    lastSyncAt,
+		"last_success_at": lastSuccessAt,
+		"last_status":     status,
+		"last_error":      lastError,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
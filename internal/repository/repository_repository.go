@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"time"
+
 	"gitlab-tool/internal/models"
 
 	"gorm.io/gorm"
@@ -20,7 +22,9 @@ func (r *RepositoryRepository) Create(repo *models.Repository) error {
 
 func (r *RepositoryRepository) FindByID(id uint) (*models.Repository, error) {
 	var repo models.Repository
-	err := r.db.Preload("Owner").First(&repo, id).Error
+	err := r.db.Preload("Owner").Preload("Languages", func(db *gorm.DB) *gorm.DB {
+		return db.Order("bytes DESC")
+	}).First(&repo, id).Error
 	if err != nil {
 		return nil, err
 	}
@@ -48,8 +52,10 @@ func (r *RepositoryRepository) FindByUsernameAndName(username, name string) (*mo
 	return &repo, nil
 }
 
+// Update saves a repository's settings. The language breakdown is left out:
+// LanguageRepository owns it, and the copy loaded with repo may be stale.
 func (r *RepositoryRepository) Update(repo *models.Repository) error {
-	return r.db.Save(repo).Error
+	return r.db.Omit("Languages", "LanguagesComputedAt").Save(repo).Error
 }
 
 func (r *RepositoryRepository) Delete(id uint) error {
@@ -64,3 +70,210 @@ func (r *RepositoryRepository) ListPublic() ([]models.Repository, error) {
 	}
 	return repos, nil
 }
+
+func (r *RepositoryRepository) FindAll() ([]models.Repository, error) {
+	var repos []models.Repository
+	err := r.db.Preload("Owner").Find(&repos).Error
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// FindReadableBy returns the repositories a user owns plus all public ones.
+func (r *RepositoryRepository) FindReadableBy(userID uint) ([]models.Repository, error) {
+	var repos []models.Repository
+	err := r.db.Where("owner_id = ? OR visibility = ?", userID, "public").Preload("Owner").Find(&repos).Error
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// RepositorySearch describes a paginated repository listing.
+type RepositorySearch struct {
+	Query string
+	// ViewerID's own private repositories are included; 0 lists public ones only
+	ViewerID uint
+	// Sort is one of "updated" (default), "created" or "stars"
+	Sort   string
+	Offset int
+	Limit  int
+}
+
+// Search matches name, description, topics and owner username, returning one
+// page of results and the total number of matches.
+func (r *RepositoryRepository) Search(params RepositorySearch) ([]models.Repository, int64, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		db = db.Joins("JOIN users ON users.id = repositories.owner_id")
+		if params.ViewerID != 0 {
+			db = db.Where("repositories.visibility = ? OR repositories.owner_id = ?", "public", params.ViewerID)
+		} else {
+			db = db.Where("repositories.visibility = ?", "public")
+		}
+
+		if params.Query != "" {
+			pattern := containsPattern(params.Query)
+			db = db.Where(
+				`LOWER(repositories.name) LIKE ? ESCAPE '\' OR LOWER(repositories.description) LIKE ? ESCAPE '\' OR LOWER(repositories.topics) LIKE ? ESCAPE '\' OR LOWER(users.username) LIKE ? ESCAPE '\'`,
+				pattern, pattern, pattern, pattern,
+			)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.Model(&models.Repository{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "repositories.updated_at DESC"
+	switch params.Sort {
+	case "created":
+		order = "repositories.created_at DESC"
+	case "stars":
+		order = "repositories.stars_count DESC, repositories.updated_at DESC"
+	}
+
+	var repos []models.Repository
+	err := r.db.Scopes(filter).
+		Preload("Owner").
+		Order(order).
+		Order("repositories.id DESC").
+		Offset(params.Offset).
+		Limit(params.Limit).
+		Find(&repos).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return repos, total, nil
+}
+
+// AddStar stars a repository for a user. Starring twice is a no-op.
+func (r *RepositoryRepository) AddStar(userID, repositoryID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where(models.Star{UserID: userID, RepositoryID: repositoryID}).
+			FirstOrCreate(&models.Star{UserID: userID, RepositoryID: repositoryID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.Repository{}).Where("id = ?", repositoryID).
+			UpdateColumn("stars_count", gorm.Expr("stars_count + 1")).Error
+	})
+}
+
+// RemoveStar unstars a repository for a user. Unstarring twice is a no-op.
+func (r *RepositoryRepository) RemoveStar(userID, repositoryID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND repository_id = ?", userID, repositoryID).Delete(&models.Star{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&models.Repository{}).Where("id = ?", repositoryID).
+			UpdateColumn("stars_count", gorm.Expr("stars_count - 1")).Error
+	})
+}
+
+// FindByRedirect resolves an old owner/name path left behind by a rename or
+// transfer to the repository that now lives elsewhere.
+func (r *RepositoryRepository) FindByRedirect(username, name string) (*models.Repository, error) {
+	var redirect models.RepositoryRedirect
+	if err := r.db.Where("owner_name = ? AND name = ?", username, name).First(&redirect).Error; err != nil {
+		return nil, err
+	}
+	return r.FindByID(redirect.RepositoryID)
+}
+
+// Relocate renames a repository and/or moves it to a new owner, leaving a
+// redirect at the old path.
+func (r *RepositoryRepository) Relocate(repo *models.Repository, newOwner *models.User, newName string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Either path may hold a stale redirect from before a repository
+		// took it over
+		if err := tx.Where("(owner_name = ? AND name = ?) OR (owner_name = ? AND name = ?)",
+			newOwner.Username, newName, repo.Owner.Username, repo.Name).
+			Delete(&models.RepositoryRedirect{}).Error; err != nil {
+			return err
+		}
+		redirect := &models.RepositoryRedirect{
+			OwnerName:    repo.Owner.Username,
+			Name:         repo.Name,
+			RepositoryID: repo.ID,
+		}
+		if err := tx.Create(redirect).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("repository_id = ?", repo.ID).Delete(&models.RepositoryTransfer{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Repository{}).Where("id = ?", repo.ID).
+			Updates(map[string]interface{}{"owner_id": newOwner.ID, "name": newName}).Error
+	})
+}
+
+// FindTrashedByOwnerID lists a user's deleted repositories that have not been
+// purged yet, most recently deleted first.
+func (r *RepositoryRepository) FindTrashedByOwnerID(ownerID uint) ([]models.Repository, error) {
+	var repos []models.Repository
+	err := r.db.Unscoped().Preload("Owner").
+		Where("owner_id = ? AND deleted_at IS NOT NULL", ownerID).
+		Order("deleted_at DESC").
+		Find(&repos).Error
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+func (r *RepositoryRepository) FindTrashedByID(id uint) (*models.Repository, error) {
+	var repo models.Repository
+	err := r.db.Unscoped().Preload("Owner").Where("deleted_at IS NOT NULL").First(&repo, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &repo, nil
+}
+
+// FindTrashedBefore returns deleted repositories whose deletion is older than
+// cutoff.
+func (r *RepositoryRepository) FindTrashedBefore(cutoff time.Time) ([]models.Repository, error) {
+	var repos []models.Repository
+	err := r.db.Unscoped().Preload("Owner").Where("deleted_at IS NOT NULL AND deleted_at < ?", cutoff).Find(&repos).Error
+	if err != nil {
+		return nil, err
+	}
+	return repos, nil
+}
+
+// Restore undoes the soft delete of a repository.
+func (r *RepositoryRepository) Restore(id uint) error {
+	return r.db.Unscoped().Model(&models.Repository{}).Where("id = ?", id).Update("deleted_at", nil).Error
+}
+
+// Purge permanently deletes a repository row along with the rows that only
+// make sense while it exists.
+func (r *RepositoryRepository) Purge(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		dependents := []interface{}{
+			&models.RepositoryLanguage{},
+			&models.Star{},
+			&models.RepositoryRedirect{},
+			&models.RepositoryTransfer{},
+			&models.CommitStatus{},
+			&models.PullMirror{},
+			&models.PushMirror{},
+			&models.RepositoryImport{},
+			&models.LFSObject{},
+			&models.LFSLock{},
+			&models.RepositoryStorage{},
+			&models.RepositoryHousekeeping{},
+			&models.IntegrityIssue{},
+		}
+		for _, model := range dependents {
+			if err := tx.Unscoped().Where("repository_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Unscoped().Delete(&models.Repository{}, id).Error
+	})
+}
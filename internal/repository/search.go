@@ -0,0 +1,10 @@
+package repository
+
+import "strings"
+
+// containsPattern builds a case-insensitive LIKE pattern matching q anywhere.
+// Use it with `LOWER(column) LIKE ? ESCAPE '\'`.
+func containsPattern(q string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(strings.ToLower(q))
+	return "%" + escaped + "%"
+}
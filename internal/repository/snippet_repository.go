@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type SnippetRepository struct {
+	db *gorm.DB
+}
+
+func NewSnippetRepository(db *gorm.DB) *SnippetRepository {
+	return &SnippetRepository{db: db}
+}
+
+func (r *SnippetRepository) Create(snippet *models.Snippet) error {
+	return r.db.Create(snippet).Error
+}
+
+func (r *SnippetRepository) FindByID(id uint) (*models.Snippet, error) {
+	var snippet models.Snippet
+	err := r.db.Preload("Owner").First(&snippet, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snippet, nil
+}
+
+func (r *SnippetRepository) FindByOwnerID(ownerID uint) ([]models.Snippet, error) {
+	var snippets []models.Snippet
+	err := r.db.Where("owner_id = ?", ownerID).Order("updated_at DESC").Find(&snippets).Error
+	if err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+func (r *SnippetRepository) ListPublic() ([]models.Snippet, error) {
+	var snippets []models.Snippet
+	err := r.db.Where("visibility = ?", "public").Preload("Owner").Order("updated_at DESC").Find(&snippets).Error
+	if err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+func (r *SnippetRepository) Update(snippet *models.Snippet) error {
+	return r.db.Save(snippet).Error
+}
+
+func (r *SnippetRepository) Delete(id uint) error {
+	return r.db.Delete(&models.Snippet{}, id).Error
+}
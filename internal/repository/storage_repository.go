@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type StorageRepository struct {
+	db *gorm.DB
+}
+
+func NewStorageRepository(db *gorm.DB) *StorageRepository {
+	return &StorageRepository{db: db}
+}
+
+// SaveSize records the measured size of a repository.
+func (r *StorageRepository) SaveSize(repositoryID uint, size int64) error {
+	return r.db.Save(&models.RepositoryStorage{RepositoryID: repositoryID, Size: size}).Error
+}
+
+// RepositoryUsage is the storage used by one repository.
+type RepositoryUsage struct {
+	RepositoryID uint   `json:"repository_id"`
+	OwnerID      uint   `json:"-"`
+	Name         string `json:"name"`
+	// Size is the measured size of the git data and wiki, LFSSize the total
+	// size of the LFS objects the repository references
+	Size    int64 `json:"repository_size"`
+	LFSSize int64 `json:"lfs_size"`
+	// Measured is false until the repository has been measured once
+	Measured bool `json:"-"`
+}
+
+// Total is the storage that counts towards quotas.
+func (u RepositoryUsage) Total() int64 {
+	return u.Size + u.LFSSize
+}
+
+// FindUsage returns the usage of every repository that is not in the trash,
+// restricted to the repositories of ownerID unless it is zero.
+func (r *StorageRepository) FindUsage(ownerID uint) ([]RepositoryUsage, error) {
+	query := r.db.Table("repositories").
+		Select("repositories.id AS repository_id, repositories.owner_id, repositories.name, " +
+			"COALESCE(repository_storages.size, 0) AS size, " +
+			"repository_storages.repository_id IS NOT NULL AS measured, " +
+			"(SELECT COALESCE(SUM(lfs_objects.size), 0) FROM lfs_objects WHERE lfs_objects.repository_id = repositories.id) AS lfs_size").
+		Joins("LEFT JOIN repository_storages ON repository_storages.repository_id = repositories.id").
+		Where("repositories.deleted_at IS NULL")
+	if ownerID != 0 {
+		query = query.Where("repositories.owner_id = ?", ownerID)
+	}
+
+	var usage []RepositoryUsage
+	if err := query.Order("repositories.id").Scan(&usage).Error; err != nil {
+		return nil, err
+	}
+	return usage, nil
+}
+
+func (r *StorageRepository) FindLimit(userID uint) (*models.StorageLimit, error) {
+	var limit models.StorageLimit
+	if err := r.db.First(&limit, "user_id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+func (r *StorageRepository) FindLimits() ([]models.StorageLimit, error) {
+	var limits []models.StorageLimit
+	if err := r.db.Order("user_id").Find(&limits).Error; err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+func (r *StorageRepository) SaveLimit(limit *models.StorageLimit) error {
+	return r.db.Save(limit).Error
+}
+
+func (r *StorageRepository) DeleteLimit(userID uint) error {
+	return r.db.Delete(&models.StorageLimit{}, "user_id = ?", userID).Error
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type TransferRepository struct {
+	db *gorm.DB
+}
+
+func NewTransferRepository(db *gorm.DB) *TransferRepository {
+	return &TransferRepository{db: db}
+}
+
+func (r *TransferRepository) Create(transfer *models.RepositoryTransfer) error {
+	return r.db.Create(transfer).Error
+}
+
+func (r *TransferRepository) FindByID(id uint) (*models.RepositoryTransfer, error) {
+	var transfer models.RepositoryTransfer
+	err := r.db.Preload("Repository.Owner").Preload("FromUser").Preload("ToUser").First(&transfer, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *TransferRepository) FindByRepositoryID(repositoryID uint) (*models.RepositoryTransfer, error) {
+	var transfer models.RepositoryTransfer
+	err := r.db.Where("repository_id = ?", repositoryID).First(&transfer).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// FindForUser lists the transfers a user has been offered or has offered.
+func (r *TransferRepository) FindForUser(userID uint) ([]models.RepositoryTransfer, error) {
+	var transfers []models.RepositoryTransfer
+	err := r.db.Preload("Repository.Owner").Preload("FromUser").Preload("ToUser").
+		Where("to_user_id = ? OR from_user_id = ?", userID, userID).
+		Order("created_at DESC").
+		Find(&transfers).Error
+	if err != nil {
+		return nil, err
+	}
+	return transfers, nil
+}
+
+func (r *TransferRepository) Delete(id uint) error {
+	return r.db.Delete(&models.RepositoryTransfer{}, id).Error
+}
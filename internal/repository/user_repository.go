@@ -52,3 +52,26 @@ func (r *UserRepository) Update(user *models.User) error {
 func (r *UserRepository) Delete(id uint) error {
 	return r.db.Delete(&models.User{}, id).Error
 }
+
+// Search matches usernames, returning one page of results and the total
+// number of matches.
+func (r *UserRepository) Search(q string, offset, limit int) ([]models.User, int64, error) {
+	filter := func(db *gorm.DB) *gorm.DB {
+		if q == "" {
+			return db
+		}
+		return db.Where(`LOWER(username) LIKE ? ESCAPE '\'`, containsPattern(q))
+	}
+
+	var total int64
+	if err := r.db.Model(&models.User{}).Scopes(filter).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := r.db.Scopes(filter).Order("username ASC").Offset(offset).Limit(limit).Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
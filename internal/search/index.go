@@ -0,0 +1,136 @@
+package search
+
+import (
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// document is a single indexed file.
+type document struct {
+	Path     string
+	BlobSHA  string
+	Language string
+	Content  string
+}
+
+// repoIndex is the trigram index of the default branch of one repository.
+// Only documents are persisted; postings are rebuilt when loading.
+type repoIndex struct {
+	RepositoryID uint
+	Commit       string
+	Documents    []document
+
+	postings map[uint32][]int
+}
+
+// trigrams returns the distinct lower-cased trigrams of s, sorted.
+func trigrams(s string) []uint32 {
+	if len(s) < 3 {
+		return nil
+	}
+
+	seen := make(map[uint32]bool)
+	for i := 0; i+3 <= len(s); i++ {
+		seen[uint32(s[i])<<16|uint32(s[i+1])<<8|uint32(s[i+2])] = true
+	}
+
+	result := make([]uint32, 0, len(seen))
+	for trigram := range seen {
+		result = append(result, trigram)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+func (idx *repoIndex) buildPostings() {
+	idx.postings = make(map[uint32][]int)
+	for docID, doc := range idx.Documents {
+		for _, trigram := range trigrams(strings.ToLower(doc.Content)) {
+			idx.postings[trigram] = append(idx.postings[trigram], docID)
+		}
+	}
+}
+
+// candidates returns the documents that contain every trigram. A nil trigram
+// list matches all documents.
+func (idx *repoIndex) candidates(required []uint32) []int {
+	if len(required) == 0 {
+		all := make([]int, len(idx.Documents))
+		for i := range all {
+			all[i] = i
+		}
+		return all
+	}
+
+	result := idx.postings[required[0]]
+	for _, trigram := range required[1:] {
+		result = intersect(result, idx.postings[trigram])
+		if len(result) == 0 {
+			break
+		}
+	}
+	return result
+}
+
+// intersect merges two sorted posting lists.
+func intersect(a, b []int) []int {
+	result := []int{}
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
+
+func indexFile(dataPath string, repositoryID uint) string {
+	return filepath.Join(dataPath, fmt.Sprintf("%d.gob", repositoryID))
+}
+
+func loadIndex(dataPath string, repositoryID uint) (*repoIndex, error) {
+	file, err := os.Open(indexFile(dataPath, repositoryID))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var idx repoIndex
+	if err := gob.NewDecoder(file).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("failed to decode search index: %w", err)
+	}
+	idx.buildPostings()
+	return &idx, nil
+}
+
+// save writes the index atomically so a crash never leaves a torn file.
+func (idx *repoIndex) save(dataPath string) error {
+	if err := os.MkdirAll(dataPath, 0755); err != nil {
+		return fmt.Errorf("failed to create search index directory: %w", err)
+	}
+
+	temp, err := os.CreateTemp(dataPath, "index-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create search index file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if err := gob.NewEncoder(temp).Encode(idx); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to encode search index: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), indexFile(dataPath, idx.RepositoryID))
+}
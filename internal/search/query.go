@@ -0,0 +1,71 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Query is a parsed code search query such as
+// `repo:api path:handlers lang:go func New`.
+type Query struct {
+	Pattern string
+	Regex   bool
+	Repo    string
+	Path    string
+	Lang    string
+
+	matcher *regexp.Regexp
+}
+
+// ParseQuery splits filters from the search pattern. Everything that is not a
+// repo:, path: or lang: filter is joined back into the pattern, so literal
+// searches may contain spaces.
+func ParseQuery(raw string, regex bool) (*Query, error) {
+	query := &Query{Regex: regex}
+
+	terms := []string{}
+	for _, field := range strings.Fields(raw) {
+		key, value, found := strings.Cut(field, ":")
+		switch {
+		case found && key == "repo" && value != "":
+			query.Repo = strings.ToLower(value)
+		case found && key == "path" && value != "":
+			query.Path = strings.ToLower(value)
+		case found && key == "lang" && value != "":
+			query.Lang = strings.ToLower(value)
+		default:
+			terms = append(terms, field)
+		}
+	}
+	query.Pattern = strings.Join(terms, " ")
+
+	if query.Pattern == "" {
+		return nil, fmt.Errorf("search pattern is required")
+	}
+
+	// Literal searches are case-insensitive; regexes are used as given and
+	// can opt in with (?i)
+	expr := "(?i)" + regexp.QuoteMeta(query.Pattern)
+	if regex {
+		expr = query.Pattern
+	}
+	matcher, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	query.matcher = matcher
+
+	return query, nil
+}
+
+// requiredTrigrams returns trigrams every matching document must contain, or
+// nil if the query cannot be narrowed down (short or unanchored regexes).
+func (q *Query) requiredTrigrams() []uint32 {
+	literal := strings.ToLower(q.Pattern)
+	if q.Regex {
+		prefix, _ := q.matcher.LiteralPrefix()
+		literal = strings.ToLower(prefix)
+	}
+	return trigrams(literal)
+}
@@ -0,0 +1,243 @@
+// Package search maintains a trigram index of the default branch of every
+// repository and answers code search queries against it.
+package search
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/languages"
+	"gitlab-tool/internal/models"
+)
+
+const (
+	// Files larger than this are not indexed
+	maxFileSize = 1 << 20
+
+	maxResults         = 100
+	maxMatchesPerFile  = 10
+	maxLineLengthShown = 500
+)
+
+type Service struct {
+	dataPath   string
+	gitService *git.Service
+	updates    *jobs.Coalescer
+
+	mu      sync.RWMutex
+	indexes map[uint]*repoIndex
+}
+
+func NewService(dataPath string, gitService *git.Service) *Service {
+	return &Service{
+		dataPath:   dataPath,
+		gitService: gitService,
+		updates:    jobs.NewCoalescer(),
+		indexes:    make(map[uint]*repoIndex),
+	}
+}
+
+type LineMatch struct {
+	LineNumber int    `json:"line_number"`
+	Line       string `json:"line"`
+}
+
+type Result struct {
+	RepositoryID uint        `json:"repository_id"`
+	Repository   string      `json:"repository"`
+	Path         string      `json:"path"`
+	Language     string      `json:"language"`
+	Matches      []LineMatch `json:"matches"`
+}
+
+// Schedule updates the index of a repository in the background.
+func (s *Service) Schedule(repo *models.Repository) {
+	s.updates.Run(repo.ID, s.update(repo))
+}
+
+// IndexAll updates the index of every repository, one after another so a
+// server with many repositories is not flooded with git processes. It goes
+// through the same coalescer as Schedule, which keeps the indexing of each
+// repository serialized with push-triggered runs.
+func (s *Service) IndexAll(repos []models.Repository) {
+	for i := range repos {
+		s.updates.Do(repos[i].ID, s.update(&repos[i]))
+	}
+}
+
+func (s *Service) update(repo *models.Repository) func() {
+	return func() {
+		if err := s.IndexRepository(repo); err != nil {
+			fmt.Printf("Warning: Failed to index repository %d: %v\n", repo.ID, err)
+		}
+	}
+}
+
+// IndexRepository indexes the default branch of a repository. Files whose
+// blob is unchanged since the last run are reused, so only changed files are
+// read from git.
+func (s *Service) IndexRepository(repo *models.Repository) error {
+	repoPath := s.gitService.GetRepositoryPath(repo.Owner.Username, repo.Name)
+	previous := s.index(repo.ID)
+
+	next := &repoIndex{RepositoryID: repo.ID, Documents: []document{}}
+
+	branch, err := s.gitService.DefaultBranch(repoPath)
+	if err != nil {
+		return err
+	}
+	commit, err := s.gitService.ResolveCommit(repo.Owner.Username, repo.Name, branch)
+	if err == nil {
+		if previous != nil && previous.Commit == commit {
+			return nil
+		}
+		next.Commit = commit
+
+		existing := make(map[string]document)
+		if previous != nil {
+			for _, doc := range previous.Documents {
+				existing[doc.BlobSHA] = doc
+			}
+		}
+
+		entries, err := s.gitService.ListTree(repoPath, commit)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if entry.Type != "blob" || entry.Mode == "120000" || entry.Size > maxFileSize {
+				continue
+			}
+
+			doc, ok := existing[entry.SHA]
+			if !ok {
+				content, err := s.gitService.ReadFile(repoPath, commit, entry.Path)
+				if err != nil {
+					return err
+				}
+				// Skip binary files
+				if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+					continue
+				}
+				doc = document{BlobSHA: entry.SHA, Content: string(content)}
+			}
+
+			// Paths and languages can change even if contents don't
+			doc.Path = entry.Path
+			doc.Language = languages.DetectByName(entry.Path)
+			if doc.Language == "" && path.Ext(entry.Path) == "" {
+				doc.Language = languages.DetectByShebang([]byte(doc.Content))
+			}
+			next.Documents = append(next.Documents, doc)
+		}
+	}
+
+	next.buildPostings()
+	if err := next.save(s.dataPath); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.indexes[repo.ID] = next
+	s.mu.Unlock()
+	return nil
+}
+
+// RemoveRepository drops a repository from the index.
+func (s *Service) RemoveRepository(repositoryID uint) {
+	s.mu.Lock()
+	delete(s.indexes, repositoryID)
+	s.mu.Unlock()
+	os.Remove(indexFile(s.dataPath, repositoryID))
+}
+
+// index returns the in-memory index of a repository, loading it from disk
+// on first use.
+func (s *Service) index(repositoryID uint) *repoIndex {
+	s.mu.RLock()
+	idx, ok := s.indexes[repositoryID]
+	s.mu.RUnlock()
+	if ok {
+		return idx
+	}
+
+	idx, err := loadIndex(s.dataPath, repositoryID)
+	if err != nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	s.indexes[repositoryID] = idx
+	s.mu.Unlock()
+	return idx
+}
+
+// Search runs a query against the given repositories, which the caller must
+// already have filtered down to those the user can read.
+func (s *Service) Search(query *Query, repos []models.Repository) []Result {
+	required := query.requiredTrigrams()
+	results := []Result{}
+
+	for _, repo := range repos {
+		fullName := repo.Owner.Username + "/" + repo.Name
+		if query.Repo != "" && !strings.EqualFold(repo.Name, query.Repo) && !strings.EqualFold(fullName, query.Repo) {
+			continue
+		}
+
+		idx := s.index(repo.ID)
+		if idx == nil {
+			continue
+		}
+
+		for _, docID := range idx.candidates(required) {
+			doc := idx.Documents[docID]
+			if query.Path != "" && !strings.Contains(strings.ToLower(doc.Path), query.Path) {
+				continue
+			}
+			if query.Lang != "" && strings.ToLower(doc.Language) != query.Lang {
+				continue
+			}
+
+			matches := matchLines(query, doc.Content)
+			if len(matches) == 0 {
+				continue
+			}
+
+			results = append(results, Result{
+				RepositoryID: repo.ID,
+				Repository:   fullName,
+				Path:         doc.Path,
+				Language:     doc.Language,
+				Matches:      matches,
+			})
+			if len(results) >= maxResults {
+				return results
+			}
+		}
+	}
+	return results
+}
+
+func matchLines(query *Query, content string) []LineMatch {
+	matches := []LineMatch{}
+	for i, line := range strings.Split(content, "\n") {
+		if !query.matcher.MatchString(line) {
+			continue
+		}
+		if len(line) > maxLineLengthShown {
+			line = line[:maxLineLengthShown]
+		}
+		matches = append(matches, LineMatch{LineNumber: i + 1, Line: line})
+		if len(matches) >= maxMatchesPerFile {
+			break
+		}
+	}
+	return matches
+}
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"testing"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/models"
+)
+
+func commit(t *testing.T, gitService *git.Service, repo *models.Repository, files ...git.FileChange) {
+	t.Helper()
+	_, err := gitService.CommitFiles(gitService.GetRepositoryPath(repo.Owner.Username, repo.Name), git.CommitOptions{
+		Branch:  "main",
+		Message: "Update",
+		Author:  git.Signature{Name: "alice", Email: "alice@example.com"},
+		Files:   files,
+	})
+	if err != nil {
+		t.Fatalf("CommitFiles failed: %v", err)
+	}
+}
+
+func search(t *testing.T, service *Service, raw string, regex bool, repos []models.Repository) []Result {
+	t.Helper()
+	query, err := ParseQuery(raw, regex)
+	if err != nil {
+		t.Fatalf("ParseQuery(%q) failed: %v", raw, err)
+	}
+	return service.Search(query, repos)
+}
+
+func TestParseQuery(t *testing.T) {
+	query, err := ParseQuery("repo:alice/api path:Handlers lang:Go func  New", false)
+	if err != nil {
+		t.Fatalf("ParseQuery failed: %v", err)
+	}
+	if query.Repo != "alice/api" || query.Path != "handlers" || query.Lang != "go" || query.Pattern != "func New" {
+		t.Errorf("unexpected query %+v", query)
+	}
+
+	if _, err := ParseQuery("lang:go", false); err == nil {
+		t.Error("expected an error for a query without a pattern")
+	}
+	if _, err := ParseQuery("func(", true); err == nil {
+		t.Error("expected an error for an invalid regex")
+	}
+}
+
+func TestSearch(t *testing.T) {
+	gitService := git.NewService(t.TempDir())
+	service := NewService(t.TempDir(), gitService)
+
+	repos := []models.Repository{
+		{ID: 1, Name: "api", Owner: models.User{Username: "alice"}},
+		{ID: 2, Name: "web", Owner: models.User{Username: "alice"}},
+	}
+	for i := range repos {
+		if err := gitService.InitBareRepository("alice", repos[i].Name); err != nil {
+			t.Fatalf("InitBareRepository failed: %v", err)
+		}
+	}
+
+	commit(t, gitService, &repos[0],
+		git.FileChange{Path: "handlers/user.go", Content: []byte("package handlers\n\nfunc NewUserHandler() {}\n")},
+		git.FileChange{Path: "README.md", Content: []byte("Call NewUserHandler to start\n")},
+	)
+	commit(t, gitService, &repos[1],
+		git.FileChange{Path: "app.js", Content: []byte("const handler = newUserHandler()\n")},
+	)
+	for i := range repos {
+		if err := service.IndexRepository(&repos[i]); err != nil {
+			t.Fatalf("IndexRepository failed: %v", err)
+		}
+	}
+
+	results := search(t, service, "NewUserHandler", false, repos)
+	if len(results) != 3 {
+		t.Fatalf("expected 3 case-insensitive matches, got %+v", results)
+	}
+
+	results = search(t, service, "lang:go NewUserHandler", false, repos)
+	if len(results) != 1 || results[0].Path != "handlers/user.go" || results[0].Matches[0].LineNumber != 3 {
+		t.Errorf("unexpected lang-filtered results %+v", results)
+	}
+
+	results = search(t, service, "repo:alice/web path:app handler", false, repos)
+	if len(results) != 1 || results[0].Repository != "alice/web" {
+		t.Errorf("unexpected repo-filtered results %+v", results)
+	}
+
+	results = search(t, service, `^func \w+Handler`, true, repos)
+	if len(results) != 1 || results[0].Path != "handlers/user.go" {
+		t.Errorf("unexpected regex results %+v", results)
+	}
+
+	// Repositories the caller cannot read are never searched
+	results = search(t, service, "NewUserHandler", false, repos[1:])
+	if len(results) != 1 || results[0].RepositoryID != 2 {
+		t.Errorf("unexpected results for a restricted repository list %+v", results)
+	}
+
+	// Incremental update after a push, then reload from disk
+	commit(t, gitService, &repos[1], git.FileChange{Path: "app.js", Delete: true})
+	if err := service.IndexRepository(&repos[1]); err != nil {
+		t.Fatalf("IndexRepository failed: %v", err)
+	}
+	reloaded := NewService(service.dataPath, gitService)
+	results = search(t, reloaded, "NewUserHandler", false, repos)
+	if len(results) != 2 {
+		t.Errorf("expected deleted file to drop out of the index, got %+v", results)
+	}
+}
@@ -0,0 +1,75 @@
+package templates
+
+import (
+	"bytes"
+	"errors"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrTemplateNotFound is returned for a .gitignore or license that is not in
+// the catalogue.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// License describes a license in the catalogue. Key is its SPDX identifier.
+type License struct {
+	Key  string `json:"key"`
+	Name string `json:"name"`
+}
+
+var licenses = []License{
+	{Key: "Apache-2.0", Name: "Apache License 2.0"},
+	{Key: "BSD-3-Clause", Name: `BSD 3-Clause "New" or "Revised" License`},
+	{Key: "GPL-3.0", Name: "GNU General Public License v3.0"},
+	{Key: "MIT", Name: "MIT License"},
+}
+
+// Licenses lists the licenses a repository can be created with.
+func Licenses() []License {
+	return licenses
+}
+
+// LicenseText returns the text of a license with the copyright year and
+// holder filled in. The key is matched case-insensitively.
+func LicenseText(key string, year int, vars Vars) (License, []byte, error) {
+	for _, license := range licenses {
+		if !strings.EqualFold(license.Key, key) {
+			continue
+		}
+		content, err := files.ReadFile(path.Join("files/license", license.Key))
+		if err != nil {
+			return License{}, nil, err
+		}
+		content = bytes.ReplaceAll(content, []byte("{{YEAR}}"), []byte(strconv.Itoa(year)))
+		return license, Substitute(content, vars), nil
+	}
+	return License{}, nil, ErrTemplateNotFound
+}
+
+// GitignoreTemplates lists the names of the available .gitignore templates.
+func GitignoreTemplates() []string {
+	entries, err := files.ReadDir("files/gitignore")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Gitignore returns the canonical name and content of a .gitignore
+// template. The name is matched case-insensitively.
+func Gitignore(name string) (string, []byte, error) {
+	for _, candidate := range GitignoreTemplates() {
+		if strings.EqualFold(candidate, name) {
+			content, err := files.ReadFile(path.Join("files/gitignore", candidate))
+			return candidate, content, err
+		}
+	}
+	return "", nil, ErrTemplateNotFound
+}
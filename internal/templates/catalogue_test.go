@@ -0,0 +1,54 @@
+package templates
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestLicenseText(t *testing.T) {
+	license, content, err := LicenseText("mit", 2024, Vars{RepoName: "project", Owner: "alice"})
+	if err != nil {
+		t.Fatalf("LicenseText failed: %v", err)
+	}
+	if license.Key != "MIT" {
+		t.Errorf("key = %q, want MIT", license.Key)
+	}
+	if !strings.Contains(string(content), "Copyright (c) 2024 alice") {
+		t.Errorf("copyright line not filled in:\n%s", content)
+	}
+
+	if _, _, err := LicenseText("WTFPL", 2024, Vars{}); !errors.Is(err, ErrTemplateNotFound) {
+		t.Errorf("unknown license error = %v, want ErrTemplateNotFound", err)
+	}
+}
+
+func TestCatalogueFilesExist(t *testing.T) {
+	for _, license := range Licenses() {
+		if _, content, err := LicenseText(license.Key, 2024, Vars{}); err != nil || len(content) == 0 {
+			t.Errorf("license %s: err %v, %d bytes", license.Key, err, len(content))
+		}
+	}
+
+	names := GitignoreTemplates()
+	if len(names) == 0 {
+		t.Fatal("no gitignore templates")
+	}
+	name, _, err := Gitignore(strings.ToLower(names[0]))
+	if err != nil || name != names[0] {
+		t.Errorf("Gitignore(%q) = %q, %v", strings.ToLower(names[0]), name, err)
+	}
+}
+
+func TestReadmeCloneURL(t *testing.T) {
+	vars := Vars{RepoName: "project", Owner: "alice", CloneURL: "https://git.example.com/git/alice/project.git"}
+	for _, readmeType := range []string{"markdown", "text"} {
+		_, content, err := Readme(readmeType, "", vars)
+		if err != nil {
+			t.Fatalf("Readme(%q) failed: %v", readmeType, err)
+		}
+		if !strings.Contains(string(content), "git clone "+vars.CloneURL) || strings.Contains(string(content), "localhost") {
+			t.Errorf("%s README does not use the clone URL:\n%s", readmeType, content)
+		}
+	}
+}
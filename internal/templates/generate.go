@@ -0,0 +1,73 @@
+package templates
+
+import (
+	"fmt"
+
+	"gitlab-tool/internal/git"
+)
+
+// GenerateOptions controls how a repository is created from a template.
+type GenerateOptions struct {
+	// AllBranches copies every branch instead of only the default branch
+	AllBranches bool
+	Vars        Vars
+	Author      git.Signature
+}
+
+// Generate copies the files of the template repository at srcPath into the
+// empty repository at dstPath. Each copied branch becomes a single root
+// commit, so none of the template's history is carried over, and
+// placeholders in file contents are substituted. Submodules are not copied.
+func Generate(gitService *git.Service, srcPath, dstPath string, opts GenerateOptions) error {
+	defaultBranch, err := gitService.DefaultBranch(srcPath)
+	if err != nil {
+		return err
+	}
+
+	branches, err := gitService.ListRefs(srcPath, "refs/heads/")
+	if err != nil {
+		return err
+	}
+
+	for _, branch := range branches {
+		if !opts.AllBranches && branch != defaultBranch {
+			continue
+		}
+		if err := copyBranch(gitService, srcPath, dstPath, branch, opts); err != nil {
+			return fmt.Errorf("failed to copy branch %s: %w", branch, err)
+		}
+	}
+
+	return gitService.SetDefaultBranch(dstPath, defaultBranch)
+}
+
+func copyBranch(gitService *git.Service, srcPath, dstPath, branch string, opts GenerateOptions) error {
+	entries, err := gitService.ListTree(srcPath, "refs/heads/"+branch)
+	if err != nil {
+		return err
+	}
+
+	files := make([]git.FileChange, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type != "blob" {
+			continue
+		}
+		content, err := gitService.ReadBlob(srcPath, entry.SHA)
+		if err != nil {
+			return err
+		}
+		// A symlink's content is its target, which is left alone
+		if entry.Mode != "120000" {
+			content = Substitute(content, opts.Vars)
+		}
+		files = append(files, git.FileChange{Path: entry.Path, Content: content, Mode: entry.Mode})
+	}
+
+	_, err = gitService.CommitFiles(dstPath, git.CommitOptions{
+		Branch:  branch,
+		Message: "Initial commit",
+		Author:  opts.Author,
+		Files:   files,
+	})
+	return err
+}
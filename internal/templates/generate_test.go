@@ -0,0 +1,83 @@
+package templates
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab-tool/internal/git"
+)
+
+func TestGenerate(t *testing.T) {
+	service := git.NewService(t.TempDir())
+	author := git.Signature{Name: "alice", Email: "alice@example.com"}
+	for _, name := range []string{"template", "copy"} {
+		if err := service.InitBareRepository("alice", name); err != nil {
+			t.Fatalf("InitBareRepository failed: %v", err)
+		}
+	}
+	srcPath := service.GetRepositoryPath("alice", "template")
+	dstPath := service.GetRepositoryPath("alice", "copy")
+
+	commits := []git.CommitOptions{
+		{Branch: "main", Message: "one", Author: author, Files: []git.FileChange{
+			{Path: "README.md", Content: []byte("# {{REPO_NAME}} by {{OWNER}}\n")},
+		}},
+		{Branch: "main", Message: "two", Author: author, Files: []git.FileChange{
+			{Path: "bin/run.sh", Content: []byte("#!/bin/sh\necho {{REPO_NAME}}\n"), Mode: "100755"},
+			{Path: "logo.png", Content: []byte("\x89PNG\x00{{OWNER}}")},
+		}},
+		{Branch: "dev", Message: "dev", Author: author, Files: []git.FileChange{
+			{Path: "DEV.md", Content: []byte("dev")},
+		}},
+	}
+	for _, opts := range commits {
+		if _, err := service.CommitFiles(srcPath, opts); err != nil {
+			t.Fatalf("CommitFiles failed: %v", err)
+		}
+	}
+
+	err := Generate(service, srcPath, dstPath, GenerateOptions{
+		Vars:   Vars{RepoName: "copy", Owner: "bob"},
+		Author: author,
+	})
+	if err != nil {
+		t.Fatalf("Generate failed: %v", err)
+	}
+
+	readme, err := service.ReadFile(dstPath, "main", "README.md")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if string(readme) != "# copy by bob\n" {
+		t.Errorf("README.md = %q, want placeholders substituted", readme)
+	}
+
+	logo, err := service.ReadFile(dstPath, "main", "logo.png")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if !strings.Contains(string(logo), "{{OWNER}}") {
+		t.Errorf("binary file was modified: %q", logo)
+	}
+
+	entries, err := service.ListDirectory(dstPath, "main", "bin")
+	if err != nil || len(entries) != 1 || entries[0].Mode != "100755" {
+		t.Errorf("bin/run.sh entries = %+v (err %v), want mode 100755", entries, err)
+	}
+
+	log, err := service.Log(dstPath, "main", 10)
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	if len(log) != 1 {
+		t.Errorf("generated history has %d commits, want 1", len(log))
+	}
+
+	branches, err := service.ListRefs(dstPath, "refs/heads/")
+	if err != nil {
+		t.Fatalf("ListRefs failed: %v", err)
+	}
+	if len(branches) != 1 || branches[0] != "main" {
+		t.Errorf("branches = %v, want only main", branches)
+	}
+}
@@ -0,0 +1,60 @@
+// Package templates produces the files a new repository is seeded with,
+// either from the built-in catalogue or from a template repository.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"strings"
+)
+
+//go:embed files
+var files embed.FS
+
+// Vars are the values substituted for placeholders in template files.
+type Vars struct {
+	RepoName string
+	Owner    string
+	// CloneURL is the HTTP clone URL shown in the built-in README
+	CloneURL string
+}
+
+// Substitute replaces the {{REPO_NAME}} and {{OWNER}} placeholders in
+// content. Binary content is returned unchanged.
+func Substitute(content []byte, vars Vars) []byte {
+	if bytes.IndexByte(content, 0) >= 0 {
+		return content
+	}
+	replacer := strings.NewReplacer(
+		"{{REPO_NAME}}", vars.RepoName,
+		"{{OWNER}}", vars.Owner,
+	)
+	return []byte(replacer.Replace(string(content)))
+}
+
+// Readme returns the file name and content of the built-in README of the
+// given type ("markdown" or "text"). The title defaults to the repository
+// name.
+func Readme(readmeType, title string, vars Vars) (string, []byte, error) {
+	var filename, source string
+	switch readmeType {
+	case "", "markdown":
+		filename, source = "README.md", "files/readme/markdown.md"
+	case "text":
+		filename, source = "README.txt", "files/readme/text.txt"
+	default:
+		return "", nil, fmt.Errorf("unsupported readme type: %s", readmeType)
+	}
+
+	content, err := files.ReadFile(source)
+	if err != nil {
+		return "", nil, err
+	}
+	if title == "" {
+		title = vars.RepoName
+	}
+	content = bytes.ReplaceAll(content, []byte("{{TITLE}}"), []byte(title))
+	content = bytes.ReplaceAll(content, []byte("{{CLONE_URL}}"), []byte(vars.CloneURL))
+	return filename, Substitute(content, vars), nil
+}
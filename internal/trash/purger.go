@@ -0,0 +1,80 @@
+package trash
+
+import (
+	"fmt"
+	"time"
+
+	"gitlab-tool/internal/git"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/repository"
+)
+
+// Purger permanently removes deleted repositories once their retention
+// window has passed.
+type Purger struct {
+	repoRepo       *repository.RepositoryRepository
+	gitService     *git.Service
+	retention      time.Duration
+	purgeListeners []func(repo *models.Repository)
+}
+
+func NewPurger(repoRepo *repository.RepositoryRepository, gitService *git.Service, retention time.Duration) *Purger {
+	return &Purger{
+		repoRepo:   repoRepo,
+		gitService: gitService,
+		retention:  retention,
+	}
+}
+
+// OnPurge registers a listener that runs after a repository has been
+// permanently removed, for cleaning up derived data keyed by its ID.
+func (p *Purger) OnPurge(listener func(repo *models.Repository)) {
+	p.purgeListeners = append(p.purgeListeners, listener)
+}
+
+// Expiry returns when a deleted repository stops being restorable.
+func (p *Purger) Expiry(repo *models.Repository) time.Time {
+	return repo.DeletedAt.Time.Add(p.retention)
+}
+
+// cutoff returns the deletion time before which repositories have expired
+// at now, matching Expiry.
+func (p *Purger) cutoff(now time.Time) time.Time {
+	return now.Add(-p.retention)
+}
+
+// Start purges expired repositories now and then once per interval, until
+// the process exits.
+func (p *Purger) Start(interval time.Duration) {
+	go func() {
+		for {
+			p.PurgeExpired()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// PurgeExpired removes every repository deleted longer ago than the
+// retention window from disk and from the database.
+func (p *Purger) PurgeExpired() {
+	repos, err := p.repoRepo.FindTrashedBefore(p.cutoff(time.Now()))
+	if err != nil {
+		fmt.Printf("Warning: Failed to list expired repositories: %v\n", err)
+		return
+	}
+
+	for i := range repos {
+		repo := &repos[i]
+		if err := p.gitService.PurgeRepository(repo.ID); err != nil {
+			fmt.Printf("Warning: Failed to purge repository %d: %v\n", repo.ID, err)
+			continue
+		}
+		if err := p.repoRepo.Purge(repo.ID); err != nil {
+			fmt.Printf("Warning: Failed to purge repository %d: %v\n", repo.ID, err)
+			continue
+		}
+		for _, listener := range p.purgeListeners {
+			listener(repo)
+		}
+	}
+}
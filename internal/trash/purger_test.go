@@ -0,0 +1,30 @@
+package trash
+
+import (
+	"testing"
+	"time"
+
+	"gitlab-tool/internal/models"
+
+	"gorm.io/gorm"
+)
+
+func TestExpiry(t *testing.T) {
+	purger := NewPurger(nil, nil, 30*24*time.Hour)
+	deletedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo := &models.Repository{DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}}
+
+	expiry := purger.Expiry(repo)
+	if want := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC); !expiry.Equal(want) {
+		t.Errorf("Expiry = %v, want %v", expiry, want)
+	}
+
+	// The purge cutoff agrees with Expiry: the repository is still
+	// restorable right up to its expiry and purged after it
+	if cutoff := purger.cutoff(expiry); deletedAt.Before(cutoff) {
+		t.Errorf("repository is purged at its expiry, cutoff %v", cutoff)
+	}
+	if cutoff := purger.cutoff(expiry.Add(time.Second)); !deletedAt.Before(cutoff) {
+		t.Errorf("repository is kept after its expiry, cutoff %v", cutoff)
+	}
+}
@@ -3,13 +3,27 @@ package main
 import (
 	"log"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"gitlab-tool/internal/config"
 	"gitlab-tool/internal/database"
 	"gitlab-tool/internal/git"
 	"gitlab-tool/internal/handlers"
+	"gitlab-tool/internal/housekeeping"
+	"gitlab-tool/internal/imports"
+	"gitlab-tool/internal/integrity"
+	"gitlab-tool/internal/jobs"
+	"gitlab-tool/internal/languages"
+	"gitlab-tool/internal/lfs"
 	"gitlab-tool/internal/middleware"
+	"gitlab-tool/internal/mirror"
+	"gitlab-tool/internal/models"
+	"gitlab-tool/internal/quota"
 	"gitlab-tool/internal/repository"
+	"gitlab-tool/internal/search"
+	"gitlab-tool/internal/trash"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -33,22 +47,102 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	repoRepo := repository.NewRepositoryRepository(db)
+	statusRepo := repository.NewCommitStatusRepository(db)
+	snippetRepo := repository.NewSnippetRepository(db)
+	langRepo := repository.NewLanguageRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	transferRepo := repository.NewTransferRepository(db)
+	mirrorRepo := repository.NewMirrorRepository(db)
+	importRepo := repository.NewImportRepository(db)
+	lfsRepo := repository.NewLFSRepository(db)
+	storageRepo := repository.NewStorageRepository(db)
+	housekeepingRepo := repository.NewHousekeepingRepository(db)
+	integrityRepo := repository.NewIntegrityRepository(db)
 
-	// Initialize git service
+	// Initialize services. Everything that writes to a repository holds its
+	// shared lock, so maintenance never repacks underneath a write
+	repoLocks := jobs.NewLocks()
 	gitService := git.NewService(cfg.ReposPath)
+	gitService.AllowFileRemotes(cfg.AllowFileMirrors)
+	languageService := languages.NewService(langRepo, gitService)
+	searchService := search.NewService(filepath.Join(cfg.DataPath, "search"), gitService)
+	purger := trash.NewPurger(repoRepo, gitService, cfg.TrashRetention)
+	quotaService := quota.NewService(storageRepo, gitService, quota.Limits{Quota: cfg.UserQuota, RepositoryLimit: cfg.RepositorySizeLimit})
+	mirrorService := mirror.NewService(mirrorRepo, repoRepo, gitService, quotaService, repoLocks)
+	importService := imports.NewService(importRepo, repoRepo, statusRepo, userRepo, gitService, quotaService, repoLocks, cfg.ImportMaxSize)
+	housekeepingService := housekeeping.NewService(repoRepo, housekeepingRepo, gitService, repoLocks, housekeeping.Policy{
+		RepackPushes: cfg.HousekeepingRepackPushes,
+		GCPushes:     cfg.HousekeepingGCPushes,
+		LooseObjects: cfg.HousekeepingLooseObjects,
+		Packs:        cfg.HousekeepingMaxPacks,
+	})
+	integrityService := integrity.NewService(repoRepo, importRepo, integrityRepo, gitService, repoLocks)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(userRepo, cfg.JWTSecret)
-	repoHandler := handlers.NewRepositoryHandler(repoRepo, gitService, cfg.ReposPath)
+	repoHandler := handlers.NewRepositoryHandler(repoRepo, auditRepo, importRepo, gitService, quotaService, repoLocks, cfg.BaseURL)
+	trashHandler := handlers.NewTrashHandler(repoRepo, gitService, purger, repoLocks)
+	mirrorHandler := handlers.NewMirrorHandler(repoRepo, mirrorRepo, gitService, mirrorService, quotaService, cfg.MirrorInterval, cfg.AllowFileMirrors)
+	lfsHandler := handlers.NewLFSHandler(repoRepo, lfsRepo, lfs.NewStore(cfg.LFSPath), quotaService, cfg.BaseURL)
+	importHandler := handlers.NewImportHandler(repoRepo, importRepo, gitService, importService, quotaService, cfg.AllowFileMirrors)
+	transferHandler := handlers.NewTransferHandler(repoRepo, userRepo, transferRepo, gitService, repoLocks)
+	statusHandler := handlers.NewCommitStatusHandler(statusRepo, repoRepo, gitService)
+	wikiHandler := handlers.NewWikiHandler(repoRepo, userRepo, gitService, repoLocks)
+	snippetHandler := handlers.NewSnippetHandler(snippetRepo, userRepo, gitService)
+	markdownHandler := handlers.NewMarkdownHandler(repoRepo)
+	browseHandler := handlers.NewBrowseHandler(repoRepo, gitService, languageService, cfg.BaseURL, cfg.SSHHost)
+	searchHandler := handlers.NewSearchHandler(repoRepo, userRepo, searchService)
+	storageHandler := handlers.NewStorageHandler(userRepo, storageRepo, auditRepo, quotaService)
+	housekeepingHandler := handlers.NewHousekeepingHandler(repoRepo, housekeepingRepo, gitService, housekeepingService)
+	integrityHandler := handlers.NewIntegrityHandler(integrityRepo, auditRepo, integrityService)
+
+	// Background work after pushes
+	repoHandler.OnPush(languageService.Schedule)
+	repoHandler.OnPush(searchService.Schedule)
+	mirrorService.OnSync(languageService.Schedule)
+	mirrorService.OnSync(searchService.Schedule)
+	repoHandler.OnPush(mirrorService.SchedulePushMirrors)
+	mirrorService.OnSync(mirrorService.SchedulePushMirrors)
+	importService.OnImport(languageService.Schedule)
+	importService.OnImport(searchService.Schedule)
+	repoHandler.OnPush(quotaService.Schedule)
+	mirrorService.OnSync(quotaService.Schedule)
+	importService.OnImport(quotaService.Schedule)
+	repoHandler.OnPush(housekeepingService.Schedule)
+	mirrorService.OnSync(housekeepingService.Schedule)
+
+	// Catch up on repositories pushed to while the server was down, and
+	// measure those created before storage quotas existed
+	go func() {
+		repos, err := repoRepo.FindAll()
+		if err != nil {
+			log.Printf("Failed to load repositories for indexing and measuring: %v", err)
+			return
+		}
+		searchService.IndexAll(repos)
+		quotaService.MeasureMissing(repos)
+	}()
+	purger.OnPurge(func(repo *models.Repository) {
+		searchService.RemoveRepository(repo.ID)
+	})
+	purger.Start(time.Hour)
+	mirrorService.Start(time.Minute)
+	importService.FailInterrupted()
+	housekeepingService.FailInterrupted()
+	housekeepingService.Start(time.Hour)
+	integrityService.Start(24 * time.Hour)
+	templateHandler := handlers.NewTemplateHandler()
 	healthHandler := handlers.NewHealthHandler()
 
 	// Setup Gin router
 	router := gin.Default()
+	// Allow URL-encoded slashes in refs such as feature%2Flogin
+	router.UseRawPath = true
 
 	// Configure CORS middleware
 	corsConfig := cors.DefaultConfig()
 	corsConfig.AllowOrigins = []string{"http://localhost:3000", "http://127.0.0.1:3000"} // Nuxt dev server
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
 	corsConfig.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"}
 	corsConfig.AllowCredentials = true
 	corsConfig.ExposeHeaders = []string{"Content-Length"}
@@ -77,21 +171,114 @@ func main() {
 	{
 		// Repository routes
 		protected.POST("/repos", repoHandler.CreateRepository)
+		protected.POST("/repos/mirror", mirrorHandler.CreateMirror)
+		protected.POST("/repos/import", importHandler.ImportRepository)
+		protected.POST("/repos/import/archive", importHandler.ImportArchive)
+		protected.GET("/imports/:id", importHandler.GetImport)
 		protected.GET("/repos", repoHandler.ListRepositories)
+		protected.GET("/repos/trash", trashHandler.ListTrash)
 		protected.GET("/repos/:id", repoHandler.GetRepository)
+		protected.PUT("/repos/:id", repoHandler.UpdateRepository)
+		protected.PATCH("/repos/:id", repoHandler.UpdateRepository)
 		protected.DELETE("/repos/:id", repoHandler.DeleteRepository)
+		protected.POST("/repos/:id/restore", trashHandler.RestoreRepository)
+		protected.POST("/repos/:id/generate", repoHandler.GenerateRepository)
+		protected.GET("/repos/:id/export", importHandler.ExportRepository)
+		protected.GET("/repos/:id/lfs", lfsHandler.GetUsage)
+		protected.GET("/repos/:id/mirror", mirrorHandler.GetMirror)
+		protected.POST("/repos/:id/mirror/sync", mirrorHandler.SyncMirror)
+		protected.DELETE("/repos/:id/mirror", mirrorHandler.DeleteMirror)
+		protected.GET("/repos/:id/push_mirrors", mirrorHandler.ListPushMirrors)
+		protected.POST("/repos/:id/push_mirrors", mirrorHandler.CreatePushMirror)
+		protected.POST("/repos/:id/push_mirrors/:mirror_id/sync", mirrorHandler.SyncPushMirror)
+		protected.DELETE("/repos/:id/push_mirrors/:mirror_id", mirrorHandler.DeletePushMirror)
+		protected.POST("/repos/:id/rename", transferHandler.RenameRepository)
+		protected.POST("/repos/:id/transfer", transferHandler.TransferRepository)
+		protected.GET("/transfers", transferHandler.ListTransfers)
+		protected.POST("/transfers/:id/accept", transferHandler.AcceptTransfer)
+		protected.DELETE("/transfers/:id", transferHandler.DeclineTransfer)
+		protected.PUT("/repos/:id/star", repoHandler.StarRepository)
+		protected.DELETE("/repos/:id/star", repoHandler.UnstarRepository)
+		protected.GET("/repos/:id/readme", browseHandler.GetReadme)
+		protected.GET("/repos/:id/summary", browseHandler.GetSummary)
+		protected.GET("/repos/:id/languages", browseHandler.GetLanguages)
+		protected.GET("/repos/:id/blame/:ref/*path", browseHandler.GetBlame)
 
 		// Git operations
 		protected.POST("/repos/:id/clone", repoHandler.CloneRepository)
 		protected.POST("/repos/:id/push", repoHandler.PushToRepository)
 		protected.POST("/repos/:id/pull", repoHandler.PullFromRepository)
+
+		// Commit status routes (for external CI systems)
+		protected.POST("/repos/:id/statuses/:sha", statusHandler.CreateStatus)
+		protected.GET("/repos/:id/statuses/:sha", statusHandler.ListStatuses)
+		protected.GET("/repos/:id/commits/:ref/status", statusHandler.GetCombinedStatus)
+
+		// Wiki routes
+		protected.GET("/repos/:id/wiki", wikiHandler.ListPages)
+		protected.POST("/repos/:id/wiki", wikiHandler.CreatePage)
+		protected.GET("/repos/:id/wiki/:page", wikiHandler.GetPage)
+		protected.PUT("/repos/:id/wiki/:page", wikiHandler.UpdatePage)
+		protected.DELETE("/repos/:id/wiki/:page", wikiHandler.DeletePage)
+
+		// Snippet routes
+		protected.POST("/snippets", snippetHandler.CreateSnippet)
+		protected.GET("/snippets", snippetHandler.ListSnippets)
+		protected.GET("/snippets/public", snippetHandler.ListPublicSnippets)
+		protected.GET("/snippets/:id", snippetHandler.GetSnippet)
+		protected.PUT("/snippets/:id", snippetHandler.UpdateSnippet)
+		protected.DELETE("/snippets/:id", snippetHandler.DeleteSnippet)
+		protected.GET("/snippets/:id/revisions", snippetHandler.ListRevisions)
+		protected.GET("/snippets/:id/raw/:filename", snippetHandler.RawFile)
+
+		// Search
+		protected.GET("/search/code", searchHandler.SearchCode)
+		protected.GET("/search/repos", searchHandler.SearchRepositories)
+		protected.GET("/search/users", searchHandler.SearchUsers)
+		protected.GET("/explore/repos", searchHandler.ExploreRepositories)
+
+		// Templates
+		protected.GET("/templates/gitignore", templateHandler.ListGitignoreTemplates)
+		protected.GET("/templates/gitignore/:name", templateHandler.GetGitignoreTemplate)
+		protected.GET("/templates/licenses", templateHandler.ListLicenses)
+		protected.GET("/templates/licenses/:key", templateHandler.GetLicense)
+
+		// Markdown rendering
+		protected.POST("/markdown", markdownHandler.Render)
+
+		// Storage usage
+		protected.GET("/user/usage", storageHandler.GetUserUsage)
+
+		// Administration
+		admin := protected.Group("/admin")
+		admin.Use(middleware.AdminMiddleware())
+		admin.GET("/usage", storageHandler.ListUsage)
+		admin.GET("/usage/:username", storageHandler.GetNamespaceUsage)
+		admin.PUT("/usage/:username/limits", storageHandler.SetLimits)
+		admin.DELETE("/usage/:username/limits", storageHandler.DeleteLimits)
+		admin.GET("/repos/:id/housekeeping", housekeepingHandler.GetHousekeeping)
+		admin.POST("/repos/:id/housekeeping", housekeepingHandler.TriggerHousekeeping)
+		admin.GET("/integrity", integrityHandler.GetReport)
+		admin.POST("/integrity/check", integrityHandler.RunCheck)
+		admin.POST("/integrity/issues/:issue_id/repair", integrityHandler.RepairIssue)
+		admin.POST("/integrity/issues/:issue_id/quarantine", integrityHandler.QuarantineIssue)
 	}
 
 	// Git HTTP backend routes (for git clone/push/pull)
 	gitGroup := router.Group("/git")
+	gitGroup.Use(middleware.GitAuthMiddleware(userRepo, cfg.JWTSecret))
 	{
+		// Snippets are served from their own namespace
+		gitGroup.Any("/snippets/:id/*action", snippetHandler.GitHTTPBackend)
 		// Use wildcard routing to capture all Git operations
-		gitGroup.Any("/:username/:repo/*action", repoHandler.GitHTTPBackend)
+		gitGroup.Any("/:username/:repo/*action", func(c *gin.Context) {
+			// Git LFS shares the repository's URL space with git itself
+			if strings.HasPrefix(c.Param("action"), "/info/lfs/") {
+				lfsHandler.ServeLFS(c)
+				return
+			}
+			repoHandler.GitHTTPBackend(c)
+		})
 	}
 
 	// Create repos directory if it doesn't exist